@@ -3,3 +3,5 @@ module github.com/sunpe/gobox
 go 1.20
 
 require golang.org/x/exp v0.0.0-20230522175609-2e198f4a06a1
+
+require go.uber.org/goleak v1.3.0
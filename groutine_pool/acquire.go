@@ -0,0 +1,44 @@
+package groutine_pool
+
+import "context"
+
+// Acquire take a worker slot of the pool for the caller, like a semaphore sharing the concurrent with the tasks.
+// it blocks until a slot is free, and returns ErrPoolClosed if the pool is closed, or ctx.Err() if ctx is done
+// before that. the slot is given back by Release
+func (g *Pool) Acquire(ctx context.Context) error {
+	for {
+		released := g.tokens.wait() // taken before trying, so a release in between is not missed
+		if ok, err := g.tryAcquire(); ok || err != nil {
+			return err
+		}
+		select {
+		case <-released:
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-g.closing:
+			return ErrPoolClosed
+		}
+	}
+}
+
+// TryAcquire take a worker slot like Acquire without blocking, and report whether it's taken.
+// it's false if the pool is at capacity or closed, like running work inline only if a slot is free
+func (g *Pool) TryAcquire() bool {
+	ok, _ := g.tryAcquire()
+	return ok
+}
+
+// Release give back a slot taken by Acquire or TryAcquire
+func (g *Pool) Release() {
+	g.tokens.release(1)
+	g.startQueued()
+}
+
+func (g *Pool) tryAcquire() (bool, error) {
+	g.RLock()
+	defer g.RUnlock()
+	if g.closed {
+		return false, ErrPoolClosed
+	}
+	return g.tokens.tryAcquire(1), nil
+}
@@ -0,0 +1,79 @@
+package groutine_pool
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// BenchmarkExecute measure fire-and-forget submission of empty tasks, run with -benchmem for allocations
+func BenchmarkExecute(b *testing.B) {
+	for _, bc := range []struct {
+		name  string
+		queue int
+	}{
+		{"Unbuffered", 0},
+		{"Buffered", 1024},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			p := NewPool(WithConcurrent(8), WithQueueSize(bc.queue))
+			defer p.Close(true)
+			f := func(context.Context) {}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				p.Execute(f)
+			}
+			p.WaitAll()
+		})
+	}
+}
+
+func BenchmarkExecuteParallel(b *testing.B) {
+	p := NewPool(WithConcurrent(8), WithQueueSize(1024))
+	defer p.Close(true)
+	f := func(context.Context) {}
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			p.Execute(f)
+		}
+	})
+	p.WaitAll()
+}
+
+// BenchmarkSkewedDurations run batches where one task in 16 is 20 times longer than the others.
+// with one shared queue the idle workers keep taking the short tasks while a long one runs, so the
+// reported utilization (busy time of the workers / (elapsed time * workers)) stays close to 1
+func BenchmarkSkewedDurations(b *testing.B) {
+	const (
+		workers = 8
+		tasks   = 256
+		short   = time.Millisecond
+		long    = 20 * short
+	)
+	p := NewPool(WithConcurrent(workers), WithQueueSize(tasks))
+	defer p.Close(true)
+
+	var busy atomic.Int64
+	b.ResetTimer()
+	start := time.Now()
+	for n := 0; n < b.N; n++ {
+		for i := 0; i < tasks; i++ {
+			d := short
+			if i%16 == 0 {
+				d = long
+			}
+			p.Execute(func(context.Context) {
+				s := time.Now()
+				time.Sleep(d)
+				busy.Add(int64(time.Since(s)))
+			})
+		}
+		p.WaitAll()
+	}
+	elapsed := time.Since(start)
+	b.ReportMetric(float64(busy.Load())/(float64(elapsed)*workers), "utilization")
+}
@@ -0,0 +1,56 @@
+package groutine_pool
+
+import (
+	"context"
+	"sync"
+)
+
+// ExecuteCancelable submit a task to the pool like Execute, and return the function to cancel the task's ctx
+// without affecting other tasks. the task is skipped if it's cancelled before starting
+func (g *Pool) ExecuteCancelable(f func(context.Context)) context.CancelFunc {
+	t := &cancelableTask{f: f, pool: g}
+	if f == nil {
+		return t.cancelTask
+	}
+	g.Execute(t.run)
+	return t.cancelTask
+}
+
+type cancelableTask struct {
+	f         func(context.Context)
+	pool      *Pool
+	mu        sync.Mutex
+	cancel    context.CancelFunc // cancel of the running task's ctx
+	cancelled bool
+}
+
+func (t *cancelableTask) run(ctx context.Context) {
+	t.start(ctx)
+}
+
+// start run the task unless it's cancelled, and report whether it ran
+func (t *cancelableTask) start(ctx context.Context) bool {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	t.mu.Lock()
+	if t.cancelled {
+		t.mu.Unlock()
+		t.pool.dropped.Add(1)
+		return false
+	}
+	t.cancel = cancel
+	t.mu.Unlock()
+
+	t.f(ctx)
+	return true
+}
+
+func (t *cancelableTask) cancelTask() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.cancelled = true
+	if t.cancel != nil {
+		t.cancel()
+	}
+}
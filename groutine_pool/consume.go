@@ -0,0 +1,22 @@
+package groutine_pool
+
+import "context"
+
+// Consume submit the tasks returned by next until it returns false, blocking while workers are busy,
+// so the pool drives an unbounded source of work at its concurrent. nil tasks are skipped.
+// it returns nil when the source is exhausted, ctx.Err() if ctx is done, or ErrPoolClosed.
+// it doesn't wait for the submitted tasks to finish
+func (g *Pool) Consume(ctx context.Context, next func() (func(context.Context), bool)) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		f, ok := next()
+		if !ok {
+			return nil
+		}
+		if err := g.SubmitContext(ctx, f); err != nil && err != ErrNilTask {
+			return err
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package groutine_pool
+
+import (
+	"context"
+	"sync"
+)
+
+// FanOut run each of fns with in concurrently in the pool, like querying several backends at once, and return
+// their outputs in the order of fns. it returns the first error, and the ctx of the other functions is cancelled
+// then. functions not started yet are skipped, and no more are submitted. a panic is returned as a "task panic" error
+func FanOut[In, Out any](p *Pool, in In, fns []func(ctx context.Context, in In) (Out, error)) ([]Out, error) {
+	outs := make([]Out, len(fns))
+	err := runAll(p, len(fns), func(ctx context.Context, i int) error {
+		out, err := fns[i](ctx, in)
+		if err == nil {
+			outs[i] = out
+		}
+		return err
+	})
+	return outs, err
+}
+
+// runAll submit fn for 0 to n-1 to the pool, and wait for them. it returns the first error or panic of fn,
+// and cancels the ctx of the other calls then. calls not started yet are skipped, and no more are submitted
+func runAll(p *Pool, n int, fn func(ctx context.Context, i int) error) error {
+	f := fanOut{cancels: map[int]context.CancelFunc{}}
+
+	var wg sync.WaitGroup
+	for i := 0; i < n && !f.failed(); i++ {
+		i := i
+		wg.Add(1)
+		err := p.submit(context.Background(), func(ctx context.Context) {
+			defer wg.Done()
+			ctx, ok := f.start(ctx, i)
+			if !ok {
+				return
+			}
+			defer f.finish(i)
+			defer func() {
+				if r := recover(); r != nil {
+					f.fail(panicError(r))
+				}
+			}()
+
+			if err := fn(ctx, i); err != nil {
+				f.fail(err)
+			}
+		}, func() {
+			defer wg.Done()
+			f.fail(ErrDiscarded)
+		})
+		if err != nil {
+			wg.Done()
+			f.fail(err)
+			break
+		}
+	}
+	wg.Wait()
+	return f.err
+}
+
+// fanOut tracks the running functions of FanOut, to cancel them on the first error
+type fanOut struct {
+	mu      sync.Mutex
+	err     error
+	cancels map[int]context.CancelFunc
+}
+
+func (f *fanOut) start(ctx context.Context, i int) (context.Context, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return nil, false
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	f.cancels[i] = cancel
+	return ctx, true
+}
+
+func (f *fanOut) finish(i int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if cancel, ok := f.cancels[i]; ok {
+		cancel()
+		delete(f.cancels, i)
+	}
+}
+
+func (f *fanOut) fail(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return
+	}
+	f.err = err
+	for _, cancel := range f.cancels {
+		cancel()
+	}
+}
+
+func (f *fanOut) failed() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.err != nil
+}
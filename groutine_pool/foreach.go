@@ -0,0 +1,12 @@
+package groutine_pool
+
+import "context"
+
+// ForEach run fn with each of items concurrently in the pool, like FanOut without results, for side-effecting
+// work. it returns the first error, and the ctx of the running calls is cancelled then. items not started yet
+// are skipped, and no more items are submitted. a panic is returned as a "task panic" error
+func ForEach[T any](p *Pool, items []T, fn func(ctx context.Context, item T) error) error {
+	return runAll(p, len(items), func(ctx context.Context, i int) error {
+		return fn(ctx, items[i])
+	})
+}
@@ -0,0 +1,77 @@
+package groutine_pool
+
+import "context"
+
+// Future is the result of a task which is available once the task finishes
+type Future[T any] struct {
+	done  chan struct{}
+	value T
+}
+
+func newFuture[T any]() *Future[T] {
+	return &Future[T]{done: make(chan struct{})}
+}
+
+func (f *Future[T]) resolve(value T) {
+	f.value = value
+	close(f.done)
+}
+
+// Done return a channel which is closed when the result is available
+func (f *Future[T]) Done() <-chan struct{} {
+	return f.done
+}
+
+// Wait block until the result is available and return it
+func (f *Future[T]) Wait() T {
+	<-f.done
+	return f.value
+}
+
+// Get is like Wait, but returns ctx.Err() if ctx is done before the result is available
+func (f *Future[T]) Get(ctx context.Context) (T, error) {
+	select {
+	case <-f.done:
+		return f.value, nil
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	}
+}
+
+// GoFunc submit f to the pool like Execute, for tasks which don't need the ctx
+func (g *Pool) GoFunc(f func()) {
+	if f == nil {
+		return
+	}
+	_ = g.Submit(func(context.Context) { f() })
+}
+
+// GoErr submit f to the pool, and return a future of its error. the future resolves to the submission error
+// if f is not accepted, to a "task panic" error if f panics, the panic is handled by the pool as well,
+// and to ErrDiscarded if f is discarded by ClearQueue
+func (g *Pool) GoErr(f func() error) *Future[error] {
+	future := newFuture[error]()
+	if f == nil {
+		future.resolve(ErrNilTask)
+		return future
+	}
+
+	err := g.submit(context.Background(), func(context.Context) {
+		var err error
+		defer func() {
+			r := recover()
+			if r == nil {
+				future.resolve(err)
+				return
+			}
+			future.resolve(panicError(r))
+			g.handlePanic(r)
+		}()
+		err = f()
+	}, func() { future.resolve(ErrDiscarded) })
+	if err != nil {
+		future.resolve(err)
+	}
+	return future
+}
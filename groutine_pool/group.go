@@ -0,0 +1,45 @@
+package groutine_pool
+
+import "sync"
+
+// taskGroup counts unfinished tasks. unlike sync.WaitGroup, add and wait can be called concurrently
+// at any time, so it can be reused while the pool keeps accepting tasks
+type taskGroup struct {
+	mu   sync.Mutex
+	n    int
+	idle chan struct{} // closed when n drops to zero
+}
+
+func (t *taskGroup) add() {
+	t.mu.Lock()
+	if t.n == 0 {
+		t.idle = make(chan struct{})
+	}
+	t.n++
+	t.mu.Unlock()
+}
+
+func (t *taskGroup) done() {
+	t.mu.Lock()
+	t.n--
+	if t.n == 0 {
+		close(t.idle)
+	}
+	t.mu.Unlock()
+}
+
+// wait return a channel which is closed when there is no unfinished task
+func (t *taskGroup) wait() <-chan struct{} {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.n == 0 {
+		return closedChan
+	}
+	return t.idle
+}
+
+var closedChan = func() chan struct{} {
+	ch := make(chan struct{})
+	close(ch)
+	return ch
+}()
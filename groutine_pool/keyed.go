@@ -0,0 +1,70 @@
+package groutine_pool
+
+import (
+	"context"
+	"sync"
+)
+
+// keyedQueue holds tasks waiting for the running task with the same key
+type keyedQueue struct {
+	mu    sync.Mutex
+	tasks map[string][]func(context.Context)
+}
+
+// ExecuteWithKey submit a task to the pool like Execute, but tasks with the same key run one at a time
+// in submission order, while tasks with different keys run in parallel.
+// tasks with the same key are run by the same worker, so they count as one against the pool concurrent
+func (g *Pool) ExecuteWithKey(key string, f func(context.Context)) *Pool {
+	if f == nil {
+		return g
+	}
+
+	g.keyed.mu.Lock()
+	if g.keyed.tasks == nil {
+		g.keyed.tasks = map[string][]func(context.Context){}
+	}
+	if queue, ok := g.keyed.tasks[key]; ok {
+		g.keyed.tasks[key] = append(queue, f)
+		g.keyed.mu.Unlock()
+		return g
+	}
+	g.keyed.tasks[key] = nil
+	g.keyed.mu.Unlock()
+
+	err := g.submit(context.Background(), func(ctx context.Context) { g.runKeyed(ctx, key, f) }, func() { g.discardKeyed(key) })
+	if err != nil {
+		g.keyed.mu.Lock()
+		delete(g.keyed.tasks, key)
+		g.keyed.mu.Unlock()
+	}
+	return g
+}
+
+// discardKeyed drop the key after its queued task is discarded by ClearQueue, with the tasks waiting behind it
+func (g *Pool) discardKeyed(key string) {
+	g.keyed.mu.Lock()
+	n := len(g.keyed.tasks[key])
+	delete(g.keyed.tasks, key)
+	g.keyed.mu.Unlock()
+	g.dropped.Add(int64(n))
+}
+
+// runKeyed run f and then the queued tasks of the key until the queue is empty
+func (g *Pool) runKeyed(ctx context.Context, key string, f func(context.Context)) {
+	for f != nil {
+		func() {
+			defer g.doRecover()
+			f(ctx)
+		}()
+
+		g.keyed.mu.Lock()
+		if queue := g.keyed.tasks[key]; len(queue) > 0 {
+			f = queue[0]
+			g.keyed.tasks[key] = queue[1:]
+		} else {
+			f = nil
+			delete(g.keyed.tasks, key)
+		}
+		g.keyed.mu.Unlock()
+	}
+}
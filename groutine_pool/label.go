@@ -0,0 +1,27 @@
+package groutine_pool
+
+import (
+	"context"
+	"runtime/pprof"
+)
+
+// taskLabelKey is the pprof label key of labeled tasks
+const taskLabelKey = "task"
+
+// ExecuteLabeled submit a task to the pool like Execute, the task runs with the pprof label task=label,
+// so goroutine profiles and DumpWorkers show what each worker is running
+func (g *Pool) ExecuteLabeled(label string, f func(context.Context)) *Pool {
+	if f == nil {
+		return g
+	}
+	return g.Execute(func(ctx context.Context) {
+		setLabel(ctx, label)
+		pprof.Do(ctx, pprof.Labels(taskLabelKey, label), f)
+	})
+}
+
+// Label return the label of the task running with ctx, it's empty if the task isn't labeled
+func Label(ctx context.Context) string {
+	label, _ := pprof.Label(ctx, taskLabelKey)
+	return label
+}
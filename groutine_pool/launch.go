@@ -0,0 +1,65 @@
+package groutine_pool
+
+import "context"
+
+// Handle controls a task submitted by Launch, its methods are safe to call from multiple goroutines
+type Handle struct {
+	task   *cancelableTask
+	future *Future[error]
+}
+
+// Launch submit a task to the pool, and return the handle to wait for or cancel it
+func (g *Pool) Launch(f func(context.Context)) *Handle {
+	h := &Handle{task: &cancelableTask{f: f, pool: g}, future: newFuture[error]()}
+	if f == nil {
+		h.future.resolve(ErrNilTask)
+		return h
+	}
+
+	err := g.submit(context.Background(), func(ctx context.Context) {
+		var err error
+		defer func() {
+			r := recover()
+			if r == nil {
+				h.future.resolve(err)
+				return
+			}
+			h.future.resolve(panicError(r))
+			g.handlePanic(r)
+		}()
+		if !h.task.start(ctx) {
+			err = context.Canceled
+		}
+	}, func() { h.future.resolve(ErrDiscarded) })
+	if err != nil {
+		h.future.resolve(err)
+	}
+	return h
+}
+
+// Wait block until the task finishes, and return the error like Err
+func (h *Handle) Wait() error {
+	return h.future.Wait()
+}
+
+// Done return a channel which is closed when the task finishes
+func (h *Handle) Done() <-chan struct{} {
+	return h.future.Done()
+}
+
+// Cancel cancel the task's ctx, the task is skipped if it hasn't started
+func (h *Handle) Cancel() {
+	h.task.cancelTask()
+}
+
+// Err return the error of the finished task: the recovered panic as a "task panic" error, context.Canceled
+// if it's cancelled before starting, ErrDiscarded if it's discarded by ClearQueue, or the submission error.
+// it's nil while the task is running
+func (h *Handle) Err() error {
+	select {
+	case <-h.future.Done():
+		return h.future.value
+	default:
+		return nil
+	}
+}
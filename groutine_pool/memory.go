@@ -0,0 +1,51 @@
+package groutine_pool
+
+import (
+	"context"
+	"sync"
+)
+
+// memoryLimit bounds the estimated bytes of accepted but unfinished tasks
+type memoryLimit struct {
+	max    int64
+	sizeOf func(f func(context.Context)) int64
+
+	mu       sync.Mutex
+	used     int64
+	released chan struct{} // closed and renewed when bytes are released
+}
+
+func newMemoryLimit(max int64, sizeOf func(f func(context.Context)) int64) *memoryLimit {
+	return &memoryLimit{max: max, sizeOf: sizeOf, released: make(chan struct{})}
+}
+
+// acquire block until size bytes fit in the limit. a task larger than the limit is accepted when nothing else
+// is in flight, so it doesn't block forever
+func (m *memoryLimit) acquire(ctx context.Context, closing <-chan struct{}, size int64) error {
+	for {
+		m.mu.Lock()
+		if m.used == 0 || m.used+size <= m.max {
+			m.used += size
+			m.mu.Unlock()
+			return nil
+		}
+		released := m.released
+		m.mu.Unlock()
+
+		select {
+		case <-released:
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-closing:
+			return ErrPoolClosed
+		}
+	}
+}
+
+func (m *memoryLimit) release(size int64) {
+	m.mu.Lock()
+	m.used -= size
+	close(m.released)
+	m.released = make(chan struct{})
+	m.mu.Unlock()
+}
@@ -0,0 +1,66 @@
+package groutine_pool
+
+import (
+	"context"
+	"sync"
+)
+
+// flights are the running executions of ExecuteOnce by key
+type flights struct {
+	mu    sync.Mutex
+	calls map[string]*flight
+}
+
+type flight struct {
+	done  chan struct{} // closed when value and err are set
+	value any
+	err   error
+}
+
+// ExecuteOnce run f in the pool and return its result, concurrent calls with the same key share one execution
+// of f and its result, like singleflight. a call after the execution finishes runs f again.
+// a panic of f is returned as a "task panic" error
+func ExecuteOnce[T any](p *Pool, key string, f func(ctx context.Context) (T, error)) (T, error) {
+	p.flights.mu.Lock()
+	if p.flights.calls == nil {
+		p.flights.calls = map[string]*flight{}
+	}
+	call, ok := p.flights.calls[key]
+	if !ok {
+		call = &flight{done: make(chan struct{})}
+		p.flights.calls[key] = call
+	}
+	p.flights.mu.Unlock()
+
+	if !ok {
+		finish := func(value any, err error) {
+			p.flights.mu.Lock()
+			delete(p.flights.calls, key)
+			p.flights.mu.Unlock()
+			call.value, call.err = value, err
+			close(call.done)
+		}
+		err := p.submit(context.Background(), func(ctx context.Context) {
+			var value T
+			var err error
+			defer func() {
+				if r := recover(); r != nil {
+					err = panicError(r)
+				}
+				finish(value, err)
+			}()
+			value, err = f(ctx)
+		}, func() {
+			var zero T
+			finish(zero, ErrDiscarded)
+		})
+		if err != nil {
+			var zero T
+			finish(zero, err)
+		}
+	}
+
+	<-call.done
+	value, _ := call.value.(T)
+	return value, call.err
+}
@@ -1,19 +1,84 @@
 package groutine_pool
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"fmt"
+	"runtime"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"golang.org/x/exp/slog"
+)
+
+var (
+	// ErrPoolClosed is returned when submitting a task to a closed pool
+	ErrPoolClosed = errors.New("pool closed")
+	// ErrNilTask is returned when submitting a nil task
+	ErrNilTask = errors.New("nil task")
+	// ErrQueueFull is returned when a task can't be accepted in time
+	ErrQueueFull = errors.New("queue full")
+	// ErrExceedConcurrent is returned when requiring more worker slots than the pool concurrent
+	ErrExceedConcurrent = errors.New("exceed pool concurrent")
+	// ErrDiscarded is the result of a queued task discarded by ClearQueue, like from Launch and ExecuteOnce
+	ErrDiscarded = errors.New("task discarded")
 )
 
 type Pool struct {
-	pending     chan func(ctx context.Context) // pending tasks when tokens is full
-	tokens      chan struct{}                  // limit goroutines by tokens bucket
-	concurrent  int                            // pool concurrent
-	idleTimeout time.Duration                  // goroutine idle
-	closed      bool
+	pending      chan func(ctx context.Context) // pending tasks when tokens is full
+	tokens       *semaphore                     // limit goroutines by tokens bucket
+	concurrent   int                            // pool concurrent
+	idleTimeout  time.Duration                  // goroutine idle
+	taskDeadline time.Duration                  // timeout of each task's ctx
+	closed       bool
+	closing      chan struct{} // closed when Close starts
+	closeOnce    sync.Once
+
+	workers  atomic.Int64   // running workers
+	peak     atomic.Int64   // high-water mark of running workers
+	rejected atomic.Int64   // submissions returning an error
+	dropped  atomic.Int64   // accepted tasks skipped without running
+	tasks    taskGroup      // submitted but unfinished tasks
+	keyed    keyedQueue     // tasks waiting for the running task with the same key
+	registry workerRegistry // running workers reported by DumpWorkers
+	ids      taskIDs        // tasks submitted by ExecuteID
+	flights  flights        // executions of ExecuteOnce
+
+	epoch   *taskGroup // tasks accepted since the last Flush
+	epochMu sync.Mutex
+
+	children subPools // created by SubPool
+
+	queueSize      int        // buffer size of pending
+	queueMu        sync.Mutex // guard workers exiting against tasks queueing, only used with buffered pending
+	queueThreshold int
+	queueExceeded  atomic.Bool
+	onQueueExceed  func(depth int)
+	softLimit      int           // queue length over which submissions are delayed
+	memory         *memoryLimit  // nil without WithMemoryLimit
+	maxDelay       time.Duration // delay when the queue is full
+
+	recoverFunc  func(r any)
+	recoverStack func(r any, stack []byte)
+	stackDepth   int            // max frames of the stack passed to recoverStack
+	spawner      func(f func()) // launch workers
+	clock        Clock
+	panics       []any // recovered panics when no recoverFunc, reported by WaitErr, up to maxPanics
+	morePanics   int   // panics over maxPanics, only counted
+	panicsMu     sync.Mutex
+
+	closedTaskHandler func(f func(context.Context)) // handle tasks submitted after close
+	syncOnClose       func() error                  // called after all workers exit in Close
+
+	recoverScope RecoverScope
 
-	recoverFunc func(r any)
+	onTaskStart func(ctx context.Context)
+	onTaskEnd   func(ctx context.Context, d time.Duration, recovered any)
+
+	slowThreshold time.Duration
+	onSlow        func(ctx context.Context, elapsed time.Duration)
 
 	ctx    context.Context // task's ctx
 	cancel context.CancelFunc
@@ -26,7 +91,11 @@ func NewPool(opts ...PoolOpt) *Pool {
 	pool := Pool{
 		concurrent:  10, // default concurrent
 		idleTimeout: time.Second,
-		pending:     make(chan func(context.Context)),
+		spawner:     func(f func()) { go f() },
+		clock:       realClock{},
+		closing:     make(chan struct{}),
+		epoch:       &taskGroup{},
+		stackDepth:  32,
 	}
 	for _, opt := range opts {
 		opt(&pool)
@@ -35,95 +104,884 @@ func NewPool(opts ...PoolOpt) *Pool {
 	if pool.ctx == nil {
 		pool.ctx = context.Background()
 	}
-	pool.tokens = make(chan struct{}, pool.concurrent)
+	pool.pending = make(chan func(context.Context), pool.queueSize)
+	pool.tokens = newSemaphore(pool.concurrent)
 	pool.ctx, pool.cancel = context.WithCancel(pool.ctx)
 
 	return &pool
 }
 
+// Execute submit a task to the pool, it blocks if workers are busy.
+// submission error is ignored and nil task is skipped, use Submit to get the error
 func (g *Pool) Execute(f func(context.Context)) *Pool {
-	defer g.doRecover()
+	_ = g.submit(context.Background(), f, nil)
+	return g
+}
+
+// Submit submit a task to the pool like Execute, and return ErrNilTask or ErrPoolClosed if it's not accepted
+func (g *Pool) Submit(f func(context.Context)) error {
+	return g.submit(context.Background(), f, nil)
+}
+
+// SubmitContext submit a task to the pool. it blocks until the task is accepted,
+// and returns ctx.Err() if ctx is done before that
+func (g *Pool) SubmitContext(ctx context.Context, f func(context.Context)) error {
+	return g.submit(ctx, f, nil)
+}
+
+// ExecuteOrTimeout submit a task to the pool, and return ErrQueueFull if it can't be accepted within acceptTimeout.
+// it returns once the task is accepted, regardless of how long the task runs
+func (g *Pool) ExecuteOrTimeout(f func(context.Context), acceptTimeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), acceptTimeout)
+	defer cancel()
+	err := g.SubmitContext(ctx, f)
+	if errors.Is(err, context.DeadlineExceeded) {
+		return ErrQueueFull
+	}
+	return err
+}
+
+// submit accept the task. onDiscard is called instead of f if the task is discarded by ClearQueue,
+// for the helpers to finish their own state of the task, it can be nil
+func (g *Pool) submit(ctx context.Context, f func(context.Context), onDiscard func()) error {
+	// nil is never enqueued, since a nil task received from pending means the pool is closed
+	if f == nil {
+		return ErrNilTask
+	}
+
+	if err := g.backoff(ctx); err != nil {
+		g.rejected.Add(1)
+		return err
+	}
+	var queued bool
+	var err error
+	var size int64
+	if g.memory != nil {
+		size = g.memory.sizeOf(f)
+		err = g.memory.acquire(ctx, g.closing, size)
+	}
+	if err == nil {
+		queued, err = g.accept(ctx, f, size, onDiscard)
+		if err != nil && g.memory != nil {
+			g.memory.release(size)
+		}
+	}
+	if err == ErrPoolClosed && onDiscard == nil { // helpers wait for their task, so they never hand it off
+		err = g.submitClosed(f)
+	}
+	if err != nil {
+		g.rejected.Add(1)
+	}
+	if queued {
+		g.checkQueue()
+	}
+	return err
+}
+
+// backoff delay the submission when the pending queue exceeds the soft limit set by WithAdaptiveBackpressure.
+// the delay grows linearly with the queue length over the soft limit, up to maxDelay when the queue is full
+func (g *Pool) backoff(ctx context.Context) error {
+	if g.maxDelay <= 0 {
+		return nil
+	}
+	depth := len(g.pending)
+	if depth <= g.softLimit {
+		return nil
+	}
+	delay := g.maxDelay
+	if room := cap(g.pending) - g.softLimit; room > 0 && depth-g.softLimit < room {
+		delay = g.maxDelay * time.Duration(depth-g.softLimit) / time.Duration(room)
+	}
+
+	timer := g.clock.NewTimer(delay)
+	defer timer.Stop()
 	select {
-	case g.pending <- f: // block if workers are busy
-	case g.tokens <- struct{}{}:
-		g.wait.Add(1)
-		go g.loop(f)
+	case <-timer.C():
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-g.closing:
+		return nil // rejected or handled as a closed task by accept
+	}
+}
+
+// accept dispatch the task while holding the read lock, so Close never closes channels under a blocked submitter.
+// size is the bytes acquired from the memory limit, released when the task finishes or is discarded
+func (g *Pool) accept(ctx context.Context, f func(context.Context), size int64, onDiscard func()) (queued bool, err error) {
+	g.RLock()
+	defer g.RUnlock()
+	if g.closed {
+		return false, ErrPoolClosed
+	}
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	task, epoch := g.track(f, size, onDiscard)
+	queued, err = g.dispatch(ctx, task)
+	if err != nil {
+		g.tasks.done()
+		epoch.done()
+	}
+	return queued, err
+}
+
+// track count the accepted task for WaitAll and Flush, and wrap it to finish the bookkeeping when it's done.
+// it must be called while holding the read lock. if the task is not dispatched, call tasks.done and epoch.done
+func (g *Pool) track(f func(context.Context), size int64, onDiscard func()) (func(context.Context), *taskGroup) {
+	g.epochMu.Lock()
+	epoch := g.epoch // a local, the closure capturing a named result moves it to the heap
+	epoch.add()
+	g.epochMu.Unlock()
+	task := func(ctx context.Context) {
+		defer epoch.done()
+		if g.memory != nil {
+			defer g.memory.release(size)
+		}
+		if ctx != discarded {
+			f(ctx)
+		} else if onDiscard != nil {
+			onDiscard()
+		}
+	}
+
+	g.tasks.add()
+	return task, epoch
+}
+
+// dispatch hand the task to a new worker or the pending queue, and report whether it's queued
+func (g *Pool) dispatch(ctx context.Context, f func(context.Context)) (queued bool, err error) {
+	if cap(g.pending) > 0 {
+		// start a worker before queueing, so queued tasks always have a running worker.
+		// workers don't exit while holding queueMu, see exitIdle
+		g.queueMu.Lock()
+		defer g.queueMu.Unlock()
+		if g.tokens.tryAcquire(1) {
+			g.spawn(f)
+			return false, nil
+		}
+	}
+
+	for {
+		released, ok := g.tokens.tryAcquireOrWait(1) // a single lock of tokens per try on the hot path
+		if ok {
+			g.spawn(f)
+			return false, nil
+		}
+
+		select {
+		case g.pending <- f: // block if workers are busy
+			return true, nil
+		case <-released:
+		case <-ctx.Done():
+			return false, ctx.Err()
+		case <-g.closing:
+			return false, ErrPoolClosed
+		}
+	}
+}
+
+// checkQueue call the queue threshold callback when the pending length crosses the threshold.
+// it's called once per crossing, and re-armed when an enqueue sees the length back under the threshold
+func (g *Pool) checkQueue() {
+	if g.onQueueExceed == nil {
+		return
+	}
+	depth := len(g.pending)
+	if depth <= g.queueThreshold {
+		g.queueExceeded.Store(false)
+		return
+	}
+	if g.queueExceeded.CompareAndSwap(false, true) {
+		g.onQueueExceed(depth)
+	}
+}
+
+// submitClosed pass the task submitted after close to the closed task handler, or return ErrPoolClosed
+func (g *Pool) submitClosed(f func(context.Context)) error {
+	if g.closedTaskHandler == nil {
+		return ErrPoolClosed
+	}
+	g.closedTaskHandler(f)
+	return nil
+}
+
+func (g *Pool) spawn(f func(context.Context)) {
+	g.acquired()
+	g.wait.Add(1)
+	g.spawner(func() { g.loop(f) })
+}
+
+// acquired records a new running worker and updates the peak
+func (g *Pool) acquired() {
+	n := g.workers.Add(1)
+	for {
+		peak := g.peak.Load()
+		if n <= peak || g.peak.CompareAndSwap(peak, n) {
+			return
+		}
 	}
-	return g
 }
 
 func (g *Pool) loop(f func(context.Context)) {
-	defer g.doRecover()
+	// wait.Done is deferred first so it runs last, Close returns only after the worker fully returns
 	defer g.wait.Done()
-	defer func() { <-g.tokens }()
+	defer g.doRecover()
 
-	timer := time.NewTimer(g.idleTimeout)
-	defer timer.Stop()
+	w := g.registry.add()
+	defer g.registry.remove(w)
+	w.ctx = context.WithValue(g.ctx, workerKey{}, w) // shared by the tasks of the worker, not built per task
+
+	var timer Timer // nil if workers never time out
+	if g.idleTimeout > 0 {
+		timer = g.clock.NewTimer(g.idleTimeout)
+		defer timer.Stop()
+	}
+
+	if f == nil { // a replacement of a worker exited by panic, see replace
+		f = g.next(timer)
+	}
+	for f != nil {
+		if g.run(w, f) && g.recoverScope == PerWorker {
+			g.replace()
+			return
+		}
+		f = g.next(timer)
+	}
+}
+
+// replace release the token of a worker exiting after a panic with PerWorker scope, and start a new worker
+// if tasks are queued. if a submitter holds queueMu, it's waiting for the released token and starts a worker
+func (g *Pool) replace() {
+	g.release()
+	if cap(g.pending) == 0 || !g.queueMu.TryLock() {
+		return
+	}
+	defer g.queueMu.Unlock()
+	g.spawnQueued()
+}
+
+// startQueued start workers for the queued tasks after tokens are released or the limit grows, like by
+// Release or SetConcurrent, since no worker may be left to take them. the released channel is notified
+// before, so a submitter holding queueMu doesn't wait for the tokens while startQueued waits for queueMu
+func (g *Pool) startQueued() {
+	if cap(g.pending) == 0 {
+		return // submitters are waiting for the released tokens themselves
+	}
+	g.queueMu.Lock()
+	defer g.queueMu.Unlock()
+	g.spawnQueued()
+}
+
+// spawnQueued start up to one worker per queued task while tokens are free, it must hold queueMu
+func (g *Pool) spawnQueued() {
+	for n := len(g.pending); n > 0 && g.tokens.tryAcquire(1); n-- {
+		g.spawn(nil)
+	}
+}
+
+// next wait for the next pending task. it returns nil and releases the worker's token
+// when the worker is idle, over the limit after SetConcurrent shrinks it, or the pool is closed
+func (g *Pool) next(timer Timer) func(context.Context) {
+	var idle <-chan time.Time // blocks forever without timer
+	if timer != nil {
+		idle = timer.C()
+	}
 
 	for {
-		f(g.ctx)
+		resized, over := g.tokens.releaseOver()
+		if over {
+			g.workers.Add(-1)
+			return nil
+		}
 
 		select {
-		case <-timer.C:
-			return
-		case f = <-g.pending:
-			if f == nil {
-				return
+		case <-resized:
+		case <-idle:
+			if g.exitIdle() {
+				return nil
+			}
+			timer.Reset(g.idleTimeout)
+		case f := <-g.pending:
+			if f == nil { // pending is closed
+				g.release()
+				return nil
 			}
 
-			if !timer.Stop() {
-				<-timer.C
+			if timer != nil {
+				if !timer.Stop() {
+					<-timer.C()
+				}
+				timer.Reset(g.idleTimeout)
 			}
-			timer.Reset(g.idleTimeout)
+			return f
 		}
 	}
 }
 
-func (g *Pool) Close(grace bool) {
+// exitIdle release the token of an idle worker, and report whether the worker can exit.
+// with a buffered queue, the worker keeps running while there are queued tasks
+func (g *Pool) exitIdle() bool {
+	if cap(g.pending) > 0 {
+		// a submitter holding queueMu may be waiting for the queue to be consumed
+		if !g.queueMu.TryLock() {
+			return false
+		}
+		defer g.queueMu.Unlock()
+		if len(g.pending) > 0 {
+			return false
+		}
+	}
+	g.release()
+	return true
+}
+
+func (g *Pool) release() {
+	g.workers.Add(-1)
+	g.tokens.release(1)
+}
+
+// WaitForCapacity block until at least n worker slots are free, or ctx is done
+func (g *Pool) WaitForCapacity(ctx context.Context, n int) error {
+	for {
+		if n > g.tokens.limit() {
+			return ErrExceedConcurrent
+		}
+		released := g.tokens.wait()
+
+		g.RLock()
+		closed := g.closed
+		g.RUnlock()
+		if closed {
+			return ErrPoolClosed
+		}
+		if g.tokens.free() >= n {
+			return nil
+		}
+
+		select {
+		case <-released:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// run a task and report whether it panicked. the panic is recovered here, so with PerTask scope
+// the worker keeps processing the queue
+func (g *Pool) run(w *worker, f func(context.Context)) (panicked bool) {
+	defer g.tasks.done()
+	w.begin(g.clock.Now())
+	defer w.end()
+
+	ctx := w.ctx
+	if ctx == nil { // not a worker of the loop, like running a task inline
+		ctx = context.WithValue(g.ctx, workerKey{}, w)
+	}
+	if g.taskDeadline > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, g.taskDeadline)
+		defer cancel()
+	}
+
+	var start time.Time
+	if g.onTaskEnd != nil || g.slowThreshold > 0 {
+		start = g.clock.Now()
+	}
+	defer func() {
+		r := recover()
+		if g.onTaskEnd != nil || g.slowThreshold > 0 {
+			elapsed := g.clock.Now().Sub(start)
+			if g.onTaskEnd != nil {
+				g.onTaskEnd(ctx, elapsed, r)
+			}
+			if g.slowThreshold > 0 && elapsed > g.slowThreshold {
+				g.onSlow(ctx, elapsed)
+			}
+		}
+		if r != nil {
+			panicked = true
+			g.handlePanic(r)
+		}
+	}()
+
+	if g.onTaskStart != nil {
+		g.onTaskStart(ctx)
+	}
+	f(ctx)
+	return false
+}
+
+// WaitAll block until all submitted tasks are finished, the pool keeps accepting tasks
+func (g *Pool) WaitAll() {
+	<-g.tasks.wait()
+}
+
+// Flush block until the tasks accepted before the call are finished, that is the pending queue at this moment
+// is drained and the tasks dequeued from it complete. the pool keeps accepting tasks during and after Flush.
+// unlike WaitAll, which returns only when there is no unfinished task at all, tasks submitted during Flush
+// are not waited for, so Flush returns even if submissions never stop
+func (g *Pool) Flush() {
+	g.epochMu.Lock()
+	epoch := g.epoch
+	g.epoch = &taskGroup{}
+	g.epochMu.Unlock()
+
+	<-epoch.wait()
+}
+
+// maxPanics is the max panics kept for WaitErr, so a pool never calling WaitErr doesn't keep all of them
+const maxPanics = 64
+
+// WaitErr block like WaitAll, and return the joined error of panics recovered since the last WaitErr call.
+// panics are only accumulated when no recover function is set by WithRecover. the first 64 panics are kept,
+// the others are only counted, and reported as one "more task panics" error
+func (g *Pool) WaitErr() error {
+	g.WaitAll()
+
+	g.panicsMu.Lock()
+	panics, more := g.panics, g.morePanics
+	g.panics, g.morePanics = nil, 0
+	g.panicsMu.Unlock()
+
+	errs := make([]error, 0, len(panics)+1)
+	for _, r := range panics {
+		errs = append(errs, panicError(r))
+	}
+	if more > 0 {
+		errs = append(errs, fmt.Errorf("%d more task panics", more))
+	}
+	return errors.Join(errs...)
+}
+
+// Close close the pool. new submissions are rejected with ErrPoolClosed first, including the blocked ones.
+// if grace is true, it waits for all the accepted tasks to finish, otherwise the context of tasks is cancelled.
+// it returns after all workers exit, and reports whether this call closed the pool, false if it was already closed.
+// the error is the joined error of the function set by WithSyncOnClose and closing the children
+func (g *Pool) Close(grace bool) (bool, error) {
+	if !g.markClosed() {
+		return false, nil
+	}
+	return true, g.shutdown(grace)
+}
+
+// CloseAndDrainTo close the pool like Close(true), but hands the queued tasks which haven't started to target
+// instead of running them, for rotating pools without losing tasks. the running tasks are waited for.
+// if target rejects a task, the rest of the queue is run by this pool, and the error of target is returned
+func (g *Pool) CloseAndDrainTo(target *Pool) error {
+	if !g.markClosed() {
+		return ErrPoolClosed
+	}
+	err := g.drainTo(target)
+	return errors.Join(err, g.shutdown(true))
+}
+
+// ClearQueue discard the queued tasks which haven't started, and return how many are discarded, like dropping
+// stale work after a feature flag flip. the running tasks, the workers and the pool are not affected.
+// discarded tasks are counted as dropped, and never run. the helpers of the pool finish them instead:
+// Launch, GoErr and ExecuteOnce return ErrDiscarded, FanOut and ForEach fail with ErrDiscarded, Await and
+// Stream skip them, and the tasks of ExecuteWithKey waiting behind a discarded one are discarded and
+// counted as dropped with it, so the key can be used again
+func (g *Pool) ClearQueue() int {
+	n := 0
+	for {
+		select {
+		case f := <-g.pending: // a task is received by either a worker or ClearQueue, never lost
+			if f == nil { // pending is closed
+				return n
+			}
+			f(discarded) // finish the bookkeeping of the task without running it
+			g.tasks.done()
+			g.dropped.Add(1)
+			n++
+		default:
+			return n
+		}
+	}
+}
+
+// discarded is the ctx passed to the queued tasks discarded by ClearQueue
+var discarded context.Context = discardedCtx{context.Background()}
+
+type discardedCtx struct{ context.Context }
+
+// drainTo submit the queued tasks to target until the queue is empty or target rejects one
+func (g *Pool) drainTo(target *Pool) error {
+	for {
+		select {
+		case f := <-g.pending:
+			// if target discards f, f finishes the bookkeeping of this pool and its helper
+			if err := target.submit(context.Background(), f, func() { f(discarded) }); err != nil {
+				_ = g.run(&worker{}, f) // f is taken from the queue, no worker will run it
+				return err
+			}
+			g.tasks.done() // the task is counted by target now
+		default:
+			return nil
+		}
+	}
+}
+
+// markClosed reject new submissions, and report whether this call closed the pool
+func (g *Pool) markClosed() bool {
+	g.closeOnce.Do(func() { close(g.closing) }) // unblock submitters holding the read lock
 	g.Lock()
+	defer g.Unlock()
 	if g.closed {
-		g.Unlock()
-		return
+		return false
 	}
 	g.closed = true
-	g.Unlock()
-
-	close(g.pending)
-	close(g.tokens)
+	return true
+}
 
-	if !grace {
+// shutdown wait for the accepted tasks or cancel them, and wait for workers to exit
+func (g *Pool) shutdown(grace bool) error {
+	err := g.closeChildren(grace)
+	if grace {
+		g.WaitAll()
+	} else {
 		g.cancel()
 	}
+
+	// no submitter is sending to the channels since closed is set
+	close(g.pending)
 	g.wait.Wait()
+	if g.syncOnClose != nil {
+		err = errors.Join(err, g.syncOnClose())
+	}
+	return err
+}
+
+// SetConcurrent change the concurrent of the pool at runtime, n less than 1 is treated as 1.
+// when growing, blocked submissions are dispatched to new workers at once.
+// when shrinking, running tasks are not interrupted, workers over the limit exit after their current task
+func (g *Pool) SetConcurrent(n int) {
+	if n < 1 {
+		n = 1
+	}
+	g.tokens.resize(n)
+	g.startQueued()
+}
+
+// Reserve take n worker slots out of the pool until release is called, so tasks of the pool can't use them,
+// like keeping capacity for another stage of a pipeline which shares the concurrent. it takes effect at once,
+// workers over the remaining slots exit after their current task. n should be less than the concurrent,
+// or no task can run until release. calling release more than once has no effect
+func (g *Pool) Reserve(n int) (release func()) {
+	if n <= 0 {
+		return func() {}
+	}
+	g.tokens.reserve(n)
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			g.tokens.release(n)
+			g.startQueued()
+		})
+	}
+}
+
+// BoostConcurrency raise the concurrent by extra for duration d, then take it back.
+// overlapping boosts stack, and each one is reverted on its own, on top of the concurrent set by SetConcurrent
+func (g *Pool) BoostConcurrency(extra int, d time.Duration) {
+	if extra <= 0 {
+		return
+	}
+	g.tokens.grow(extra)
+	g.startQueued()
+	timer := g.clock.NewTimer(d)
+	go func() {
+		defer timer.Stop()
+		select {
+		case <-timer.C():
+		case <-g.closing:
+		}
+		g.tokens.grow(-extra)
+	}()
+}
+
+// Done return a channel which is closed when the context of tasks is cancelled,
+// by Close(false) or cancellation of the context set by WithCtx
+func (g *Pool) Done() <-chan struct{} {
+	return g.ctx.Done()
+}
+
+// PoolStats is a snapshot of the pool status
+type PoolStats struct {
+	Concurrent  int // max concurrent workers
+	Workers     int // running workers
+	PeakWorkers int // max running workers observed since created or last ResetPeak
+	Pending     int // queued tasks
+	Rejected    int // submissions rejected by close, ctx or timeout, nil tasks are not counted
+	Dropped     int // tasks skipped without running, cancelled by ExecuteCancelable or ticks skipped by ScheduleEvery
+}
+
+// Stats return a snapshot of the pool status
+func (g *Pool) Stats() PoolStats {
+	return PoolStats{
+		Concurrent:  g.tokens.limit(),
+		Workers:     int(g.workers.Load()),
+		PeakWorkers: int(g.peak.Load()),
+		Pending:     len(g.pending),
+		Rejected:    int(g.rejected.Load()),
+		Dropped:     int(g.dropped.Load()),
+	}
+}
+
+// ResetPeak reset PeakWorkers to the current running workers, so the peak can be sampled per window
+func (g *Pool) ResetPeak() {
+	g.peak.Store(g.workers.Load())
 }
 
 func (g *Pool) doRecover() {
-	if r := recover(); r != nil && g.recoverFunc != nil {
+	if r := recover(); r != nil {
+		g.handlePanic(r)
+	}
+}
+
+// panicError return the recovered value as a "task panic" error, wrapping it if it's an error
+func panicError(r any) error {
+	if err, ok := r.(error); ok {
+		return fmt.Errorf("task panic: %w", err)
+	}
+	return fmt.Errorf("task panic: %v", r)
+}
+
+// handlePanic pass the recovered value to the recover function, or accumulate it for WaitErr.
+// it must be called by the deferred function which recovers, see stack
+func (g *Pool) handlePanic(r any) {
+	if g.recoverStack != nil {
+		g.recoverStack(r, g.stack())
+		return
+	}
+	if g.recoverFunc != nil {
 		g.recoverFunc(r)
+		return
+	}
+	g.panicsMu.Lock()
+	if len(g.panics) < maxPanics {
+		g.panics = append(g.panics, r)
+	} else {
+		g.morePanics++
+	}
+	g.panicsMu.Unlock()
+}
+
+// stack return up to stackDepth frames of the panicking goroutine, called by handlePanic
+func (g *Pool) stack() []byte {
+	pcs := make([]uintptr, g.stackDepth)
+	// skip [runtime.Callers, stack, handlePanic, the deferred function]
+	n := runtime.Callers(4, pcs)
+	if n == 0 {
+		return nil
+	}
+	frames := runtime.CallersFrames(pcs[:n])
+
+	var buf bytes.Buffer
+	for {
+		frame, more := frames.Next()
+		fmt.Fprintf(&buf, "%s\n\t%s:%d\n", frame.Function, frame.File, frame.Line)
+		if !more {
+			break
+		}
 	}
+	return buf.Bytes()
 }
 
 type PoolOpt func(pool *Pool)
 
+// WithCtx set the parent of the task's ctx. tasks are cancelled when ctx is done, and the pool stops accepting tasks,
+// so a pool created with a request ctx dies with the request. use WithDetachedCtx for background work
 func WithCtx(ctx context.Context) PoolOpt {
 	return func(pool *Pool) {
 		pool.ctx = ctx
 	}
 }
 
+// WithDetachedCtx set ctx as the parent of the task's ctx like WithCtx, but only its values are kept.
+// its cancellation and deadline are not, so tasks outlive ctx and are only cancelled by Close(false)
+func WithDetachedCtx(ctx context.Context) PoolOpt {
+	return func(pool *Pool) {
+		pool.ctx = detachedCtx{parent: ctx}
+	}
+}
+
+// detachedCtx keeps the values of parent, but is never done
+type detachedCtx struct {
+	parent context.Context
+}
+
+func (c detachedCtx) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (c detachedCtx) Done() <-chan struct{}       { return nil }
+func (c detachedCtx) Err() error                  { return nil }
+func (c detachedCtx) Value(key any) any           { return c.parent.Value(key) }
+
 func WithConcurrent(concurrent int) PoolOpt {
 	return func(pool *Pool) {
 		pool.concurrent = concurrent
 	}
 }
 
+// WithIdleTimeout set how long an idle worker waits for tasks before exiting. default is 1 second.
+// a non-positive timeout means workers never time out, and keep running until Close
 func WithIdleTimeout(timeout time.Duration) PoolOpt {
 	return func(pool *Pool) {
 		pool.idleTimeout = timeout
 	}
 }
+
+// WithQueueSize set the buffer size of pending tasks, tasks are queued instead of blocking the submitter
+// when workers are busy. default is 0, means unbuffered
+func WithQueueSize(size int) PoolOpt {
+	return func(pool *Pool) {
+		pool.queueSize = size
+	}
+}
+
+// WithQueueThreshold set the callback which is called when an enqueue pushes the pending length past n.
+// it's called once until an enqueue sees the length back under n
+func WithQueueThreshold(n int, onExceed func(depth int)) PoolOpt {
+	return func(pool *Pool) {
+		pool.queueThreshold = n
+		pool.onQueueExceed = onExceed
+	}
+}
+
+// WithAdaptiveBackpressure delay submissions when the pending queue is longer than softLimit, in proportion to
+// the queue length over softLimit, up to maxDelay when the queue is full. it slows producers down rather than
+// blocking them at once, and only takes effect with WithQueueSize larger than softLimit.
+// the delay of SubmitContext ends early with ctx.Err() when ctx is done, and Execute and Submit wait it out
+func WithAdaptiveBackpressure(softLimit int, maxDelay time.Duration) PoolOpt {
+	return func(pool *Pool) {
+		pool.softLimit = softLimit
+		pool.maxDelay = maxDelay
+	}
+}
+
+// WithMemoryLimit block submissions while the estimated bytes of accepted and unfinished tasks would exceed
+// maxBytes, sizeOf estimates the bytes of a task. a task larger than maxBytes is accepted when no other task
+// is in flight, so it runs alone instead of blocking forever
+func WithMemoryLimit(maxBytes int64, sizeOf func(f func(context.Context)) int64) PoolOpt {
+	return func(pool *Pool) {
+		pool.memory = newMemoryLimit(maxBytes, sizeOf)
+	}
+}
+
+// WithTaskDeadline set the timeout of each task's ctx from when the task starts. default is 0, means no timeout
+func WithTaskDeadline(d time.Duration) PoolOpt {
+	return func(pool *Pool) {
+		pool.taskDeadline = d
+	}
+}
+
 func WithRecover(f func(r any)) PoolOpt {
 	return func(pool *Pool) {
 		pool.recoverFunc = f
 	}
 }
+
+// WithRecoverStack set the recover function which also receives the stack of the panic.
+// it takes precedence over WithRecover
+func WithRecoverStack(f func(r any, stack []byte)) PoolOpt {
+	return func(pool *Pool) {
+		pool.recoverStack = f
+	}
+}
+
+// RecoverScope is where panics of tasks are recovered, see WithRecoverScope
+type RecoverScope int
+
+const (
+	// PerTask recover each task, the worker keeps running
+	PerTask RecoverScope = iota
+	// PerWorker recover the worker, which exits after the panic and is replaced by a new worker if tasks are queued
+	PerWorker
+)
+
+// WithRecoverScope set where panics of tasks are recovered. default is PerTask, so a panicking task
+// doesn't cost a worker. panics are passed to the recover function in both scopes
+func WithRecoverScope(scope RecoverScope) PoolOpt {
+	return func(pool *Pool) {
+		pool.recoverScope = scope
+	}
+}
+
+// WithPanicStackDepth set the max frames of the stack passed to the function of WithRecoverStack. default is 32,
+// 0 or less passes a nil stack
+func WithPanicStackDepth(n int) PoolOpt {
+	return func(pool *Pool) {
+		if n < 0 {
+			n = 0
+		}
+		pool.stackDepth = n
+	}
+}
+
+// WithClock set the time source of the pool, like a fake clock to trigger idle timeout in tests. default is the real time
+func WithClock(clock Clock) PoolOpt {
+	return func(pool *Pool) {
+		pool.clock = clock
+	}
+}
+
+// WithSpawner set the function used to launch workers. default is the go statement.
+// the spawner must run f asynchronously, or the submission blocks until the worker exits
+func WithSpawner(fn func(f func())) PoolOpt {
+	return func(pool *Pool) {
+		pool.spawner = fn
+	}
+}
+
+// WithClosedTaskHandler set the function handling tasks submitted after the pool is closed,
+// like running them inline or logging and dropping. default is rejecting them with ErrPoolClosed.
+// tasks of the helpers waiting for their result, like GoErr, Launch, ExecuteID, ExecuteWithKey, ExecuteOnce,
+// FanOut, ForEach and Stream, are not passed to fn and fail with ErrPoolClosed, since a dropped task would
+// leave them waiting forever
+func WithClosedTaskHandler(fn func(f func(context.Context))) PoolOpt {
+	return func(pool *Pool) {
+		pool.closedTaskHandler = fn
+	}
+}
+
+// WithSyncOnClose set the function called by Close after all workers exit, like logger.Sync,
+// so that logs written by late-finishing tasks are not lost
+func WithSyncOnClose(fn func() error) PoolOpt {
+	return func(pool *Pool) {
+		pool.syncOnClose = fn
+	}
+}
+
+// WithTaskHook set the functions called before and after each task with the task's ctx.
+// onEnd receives the duration of the task, and the recovered value if the task panicked, otherwise nil.
+// either of them can be nil
+func WithTaskHook(onStart func(ctx context.Context), onEnd func(ctx context.Context, d time.Duration, recovered any)) PoolOpt {
+	return func(pool *Pool) {
+		pool.onTaskStart = onStart
+		pool.onTaskEnd = onEnd
+	}
+}
+
+// WithSlowTaskThreshold call onSlow with the task's ctx after a task ran longer than d, including panicked tasks.
+// a nil onSlow logs a warning by the default slog logger with the elapsed time and the task label,
+// which is the logger package's if it's used. d <= 0 disables it
+func WithSlowTaskThreshold(d time.Duration, onSlow func(ctx context.Context, elapsed time.Duration)) PoolOpt {
+	return func(pool *Pool) {
+		if onSlow == nil {
+			onSlow = warnSlow
+		}
+		pool.slowThreshold = d
+		pool.onSlow = onSlow
+	}
+}
+
+func warnSlow(ctx context.Context, elapsed time.Duration) {
+	args := []any{"elapsed", elapsed}
+	if w, ok := ctx.Value(workerKey{}).(*worker); ok {
+		w.mu.Lock()
+		if w.label != "" {
+			args = append(args, "label", w.label)
+		}
+		w.mu.Unlock()
+	}
+	slog.Default().WarnCtx(ctx, "slow task", args...)
+}
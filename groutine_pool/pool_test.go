@@ -0,0 +1,382 @@
+package groutine_pool
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/goleak"
+)
+
+// waitFor poll cond until it's true, and fail the test after a few seconds
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timeout waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func workerID(ctx context.Context) int64 {
+	return ctx.Value(workerKey{}).(*worker).id
+}
+
+// runRecoverScope run a panicking task followed by queued tasks on a single worker,
+// and return the worker ids of the tasks before and after the panic
+func runRecoverScope(t *testing.T, scope RecoverScope) (before int64, after []int64) {
+	t.Helper()
+	var recovered []any
+	var mu sync.Mutex
+	p := NewPool(WithConcurrent(1), WithQueueSize(16), WithRecoverScope(scope), WithRecover(func(r any) {
+		mu.Lock()
+		recovered = append(recovered, r)
+		mu.Unlock()
+	}))
+
+	block := make(chan struct{})
+	p.Execute(func(ctx context.Context) {
+		before = workerID(ctx)
+		<-block // queue the following tasks behind the panicking one
+	})
+	p.Execute(func(context.Context) { panic("boom") })
+	for i := 0; i < 5; i++ {
+		p.Execute(func(ctx context.Context) {
+			mu.Lock()
+			after = append(after, workerID(ctx))
+			mu.Unlock()
+		})
+	}
+	close(block)
+	p.WaitAll()
+
+	if len(recovered) != 1 || recovered[0] != "boom" {
+		t.Fatalf("recovered %v, want [boom]", recovered)
+	}
+	if len(after) != 5 {
+		t.Fatalf("%d tasks ran after the panic, want 5", len(after))
+	}
+	if stats := p.Stats(); stats.Workers != 1 || stats.Pending != 0 {
+		t.Fatalf("stats %+v, want 1 worker and no pending task", stats)
+	}
+	if free := p.tokens.free(); free != 0 {
+		t.Fatalf("free tokens %d while the worker is idle, want 0", free)
+	}
+
+	if _, err := p.Close(true); err != nil {
+		t.Fatal(err)
+	}
+	if free, limit := p.tokens.free(), p.tokens.limit(); free != limit {
+		t.Fatalf("free tokens %d after close, want %d", free, limit)
+	}
+	if workers := p.Stats().Workers; workers != 0 {
+		t.Fatalf("%d workers after close, want 0", workers)
+	}
+	return before, after
+}
+
+func TestRecoverScopePerTask(t *testing.T) {
+	before, after := runRecoverScope(t, PerTask)
+	for _, id := range after {
+		if id != before {
+			t.Fatalf("task ran on worker %d, want the surviving worker %d", id, before)
+		}
+	}
+}
+
+func TestRecoverScopePerWorker(t *testing.T) {
+	before, after := runRecoverScope(t, PerWorker)
+	for _, id := range after {
+		if id == before {
+			t.Fatalf("task ran on the panicked worker %d, want its replacement", id)
+		}
+		if id != after[0] {
+			t.Fatalf("queued tasks ran on workers %v, want one replacement", after)
+		}
+	}
+}
+
+func TestRecoverScopePerWorkerUnbuffered(t *testing.T) {
+	p := NewPool(WithConcurrent(2), WithRecoverScope(PerWorker), WithRecover(func(any) {}))
+	for i := 0; i < 20; i++ {
+		i := i
+		p.Execute(func(context.Context) {
+			if i%3 == 0 {
+				panic(i)
+			}
+		})
+	}
+	p.WaitAll()
+	if _, err := p.Close(true); err != nil {
+		t.Fatal(err)
+	}
+	if free, limit := p.tokens.free(), p.tokens.limit(); free != limit {
+		t.Fatalf("free tokens %d after close, want %d", free, limit)
+	}
+}
+
+func TestCloseLeavesNoGoroutine(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+
+	p := NewPool(WithConcurrent(4), WithQueueSize(8), WithIdleTimeout(time.Hour), WithRecover(func(any) {}))
+	stop := p.ScheduleEvery(time.Millisecond, func(context.Context) {})
+	defer stop()
+	p.BoostConcurrency(2, time.Hour)
+	for i := 0; i < 50; i++ {
+		i := i
+		p.Execute(func(context.Context) {
+			if i%10 == 0 {
+				panic(i)
+			}
+		})
+	}
+	if _, err := p.Close(true); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCloseWithoutGraceLeavesNoGoroutine(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+
+	p := NewPool(WithConcurrent(2), WithQueueSize(2))
+	for i := 0; i < 4; i++ {
+		p.Execute(func(ctx context.Context) { <-ctx.Done() })
+	}
+	if _, err := p.Close(false); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestExecuteWeightedConcurrent(t *testing.T) {
+	p := NewPool(WithConcurrent(2))
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := p.ExecuteWeighted(2, func(context.Context) { time.Sleep(time.Millisecond) }); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	p.WaitAll()
+
+	if err := p.ExecuteWeighted(3, func(context.Context) {}); err != ErrExceedConcurrent {
+		t.Fatalf("weight over the concurrent: %v, want ErrExceedConcurrent", err)
+	}
+	if _, err := p.Close(true); err != nil {
+		t.Fatal(err)
+	}
+	if free, limit := p.tokens.free(), p.tokens.limit(); free != limit {
+		t.Fatalf("free tokens %d after close, want %d", free, limit)
+	}
+}
+
+func TestReserveAllThenRelease(t *testing.T) {
+	p := NewPool(WithConcurrent(2), WithQueueSize(8), WithIdleTimeout(time.Millisecond))
+	release := p.Reserve(2)
+	ran := make(chan struct{})
+	p.Execute(func(context.Context) { close(ran) })
+	waitFor(t, "workers to exit", func() bool { return p.Stats().Workers == 0 })
+
+	release()
+	select {
+	case <-ran:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("queued task stranded after release, stats %+v", p.Stats())
+	}
+	if _, err := p.Close(true); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSetConcurrentStartsQueued(t *testing.T) {
+	p := NewPool(WithConcurrent(1), WithQueueSize(8), WithIdleTimeout(time.Millisecond))
+	release := p.Reserve(1)
+	defer release()
+	var wg sync.WaitGroup
+	wg.Add(3)
+	for i := 0; i < 3; i++ {
+		p.Execute(func(context.Context) { wg.Done() })
+	}
+	waitFor(t, "workers to exit", func() bool { return p.Stats().Workers == 0 })
+
+	p.SetConcurrent(4)
+	wg.Wait()
+	if _, err := p.Close(true); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestClearQueueFinishesHelpers(t *testing.T) {
+	p := NewPool(WithConcurrent(1), WithQueueSize(64))
+	block := make(chan struct{})
+	p.Execute(func(context.Context) { <-block })
+	waitFor(t, "the blocking task to start", func() bool { return p.Stats().Workers == 1 && p.Stats().Pending == 0 })
+
+	var keyedRan bool
+	p.ExecuteWithKey("k", func(context.Context) {})
+	p.ExecuteWithKey("k", func(context.Context) { keyedRan = true }) // waits behind the discarded one
+	id := p.ExecuteID(func(context.Context) {})
+	handle := p.Launch(func(context.Context) {})
+	future := p.GoErr(func() error { return nil })
+	stream := Stream(p, []func(context.Context) int{func(context.Context) int { return 1 }})
+
+	errs := make(chan error, 3)
+	go func() {
+		_, err := ExecuteOnce(p, "once", func(context.Context) (int, error) { return 1, nil })
+		errs <- err
+	}()
+	go func() {
+		_, err := FanOut(p, 0, []func(context.Context, int) (int, error){
+			func(context.Context, int) (int, error) { return 1, nil },
+		})
+		errs <- err
+	}()
+	go func() {
+		errs <- ForEach(p, []int{1}, func(context.Context, int) error { return nil })
+	}()
+	waitFor(t, "the tasks to queue", func() bool { return p.Stats().Pending == 8 })
+
+	if n := p.ClearQueue(); n != 8 {
+		t.Fatalf("ClearQueue discarded %d tasks, want 8", n)
+	}
+	close(block)
+
+	p.Await(id)
+	if err := handle.Wait(); err != ErrDiscarded {
+		t.Fatalf("Launch: %v, want ErrDiscarded", err)
+	}
+	if err := future.Wait(); err != ErrDiscarded {
+		t.Fatalf("GoErr: %v, want ErrDiscarded", err)
+	}
+	if _, ok := <-stream; ok {
+		t.Fatal("Stream sent the result of a discarded task")
+	}
+	for i := 0; i < 3; i++ {
+		if err := <-errs; err != ErrDiscarded {
+			t.Fatalf("ExecuteOnce, FanOut or ForEach: %v, want ErrDiscarded", err)
+		}
+	}
+
+	// the keys can be used again
+	ran := make(chan struct{})
+	p.ExecuteWithKey("k", func(context.Context) { close(ran) })
+	<-ran
+	if v, err := ExecuteOnce(p, "once", func(context.Context) (int, error) { return 2, nil }); v != 2 || err != nil {
+		t.Fatalf("ExecuteOnce after discarding: %v, %v", v, err)
+	}
+	p.WaitAll()
+	if keyedRan {
+		t.Fatal("the keyed task behind a discarded one ran")
+	}
+	if dropped := p.Stats().Dropped; dropped != 9 {
+		t.Fatalf("dropped %d, want 9", dropped)
+	}
+	if _, err := p.Close(true); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestClearQueueReleasesFlush(t *testing.T) {
+	p := NewPool(WithConcurrent(1), WithQueueSize(8), WithMemoryLimit(10, func(func(context.Context)) int64 { return 5 }))
+	block := make(chan struct{})
+	p.Execute(func(context.Context) { <-block })
+	p.Execute(func(context.Context) {})
+	if n := p.ClearQueue(); n != 1 {
+		t.Fatalf("ClearQueue discarded %d tasks, want 1", n)
+	}
+	close(block)
+	p.Flush()
+	p.Execute(func(context.Context) {}) // blocks if the memory of the discarded task is kept
+	p.Execute(func(context.Context) {})
+	p.WaitAll()
+	if _, err := p.Close(true); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestClosedTaskHandlerSkipsHelpers(t *testing.T) {
+	var handled int
+	p := NewPool(WithClosedTaskHandler(func(func(context.Context)) { handled++ })) // drops the task
+	if _, err := p.Close(true); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := p.Submit(func(context.Context) {}); err != nil || handled != 1 {
+		t.Fatalf("Submit: %v with %d handled tasks, want nil and 1", err, handled)
+	}
+	if err := p.GoErr(func() error { return nil }).Wait(); err != ErrPoolClosed {
+		t.Fatalf("GoErr: %v, want ErrPoolClosed", err)
+	}
+	if err := p.Launch(func(context.Context) {}).Wait(); err != ErrPoolClosed {
+		t.Fatalf("Launch: %v, want ErrPoolClosed", err)
+	}
+	if _, err := ExecuteOnce(p, "k", func(context.Context) (int, error) { return 1, nil }); err != ErrPoolClosed {
+		t.Fatalf("ExecuteOnce: %v, want ErrPoolClosed", err)
+	}
+	if _, err := FanOut(p, 0, []func(context.Context, int) (int, error){
+		func(context.Context, int) (int, error) { return 1, nil },
+	}); err != ErrPoolClosed {
+		t.Fatalf("FanOut: %v, want ErrPoolClosed", err)
+	}
+	if err := ForEach(p, []int{1}, func(context.Context, int) error { return nil }); err != ErrPoolClosed {
+		t.Fatalf("ForEach: %v, want ErrPoolClosed", err)
+	}
+	for range Stream(p, []func(context.Context) int{func(context.Context) int { return 1 }}) {
+		t.Fatal("Stream sent the result of a rejected task")
+	}
+	p.Await(p.ExecuteID(func(context.Context) {}))
+	p.ExecuteWithKey("k", func(context.Context) {})
+	if n := len(p.keyed.tasks); n != 0 {
+		t.Fatalf("%d keys left after rejecting ExecuteWithKey, want 0", n)
+	}
+	if handled != 1 {
+		t.Fatalf("%d tasks handled, want only the one of Submit", handled)
+	}
+}
+
+func TestPanicStackDepth(t *testing.T) {
+	for _, depth := range []int{-1, 0, 1} {
+		var stack []byte
+		p := NewPool(WithPanicStackDepth(depth), WithRecoverStack(func(_ any, s []byte) { stack = s }))
+		p.Execute(func(context.Context) { panic("boom") })
+		p.WaitAll()
+		if _, err := p.Close(true); err != nil {
+			t.Fatal(err)
+		}
+		if depth <= 0 && stack != nil {
+			t.Errorf("depth %d: stack %q, want nil", depth, stack)
+		}
+		if depth > 0 && bytes.Count(stack, []byte("\n\t")) != depth {
+			t.Errorf("depth %d: stack %q, want %d frames", depth, stack, depth)
+		}
+	}
+}
+
+func TestWaitErrKeepsMaxPanics(t *testing.T) {
+	p := NewPool(WithConcurrent(4))
+	for i := 0; i < maxPanics+6; i++ {
+		i := i
+		p.Execute(func(context.Context) { panic(i) })
+	}
+	err := p.WaitErr()
+	errs := err.(interface{ Unwrap() []error }).Unwrap()
+	if len(errs) != maxPanics+1 {
+		t.Fatalf("%d errors, want %d", len(errs), maxPanics+1)
+	}
+	if last := errs[maxPanics].Error(); last != "6 more task panics" {
+		t.Fatalf("last error %q, want the count of the other panics", last)
+	}
+	if err := p.WaitErr(); err != nil {
+		t.Fatalf("second WaitErr: %v, want nil", err)
+	}
+	if _, err := p.Close(true); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,51 @@
+package groutine_pool
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ScheduleEvery submit f to the pool on each tick of d, until stop is called or the pool is closed.
+// a tick is skipped if the previous invocation is still running
+func (g *Pool) ScheduleEvery(d time.Duration, f func(context.Context)) (stop func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var once sync.Once
+	stop = func() { once.Do(cancel) }
+
+	var running atomic.Bool
+	task := func(ctx context.Context) {
+		defer running.Store(false)
+		f(ctx)
+	}
+
+	go func() {
+		defer stop()
+		ticker := time.NewTicker(d)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-g.Done():
+				return
+			case <-g.closing:
+				return
+			case <-ticker.C:
+				if !running.CompareAndSwap(false, true) {
+					g.dropped.Add(1)
+					continue
+				}
+				if err := g.submit(ctx, task, func() { running.Store(false) }); err != nil {
+					running.Store(false)
+					if err == ErrPoolClosed {
+						return
+					}
+				}
+			}
+		}
+	}()
+
+	return stop
+}
@@ -0,0 +1,121 @@
+package groutine_pool
+
+import "sync"
+
+// semaphore limits running workers like a tokens bucket, but its size can be changed at any time
+type semaphore struct {
+	mu       sync.Mutex
+	size     int           // set by WithConcurrent and SetConcurrent
+	boost    int           // extra tokens added by BoostConcurrency
+	used     int           // may exceed the limit after shrinking, until workers over the limit exit
+	released chan struct{} // closed and renewed when tokens are released or the limit grows
+	resized  chan struct{} // closed and renewed when the limit changes
+}
+
+func newSemaphore(size int) *semaphore {
+	return &semaphore{
+		size:     size,
+		released: make(chan struct{}),
+		resized:  make(chan struct{}),
+	}
+}
+
+func (s *semaphore) limit() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.size + s.boost
+}
+
+// free return the tokens available now, it's negative if the limit is shrunk below the used tokens
+func (s *semaphore) free() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.size + s.boost - s.used
+}
+
+func (s *semaphore) tryAcquire(n int) bool {
+	_, ok := s.tryAcquireOrWait(n)
+	return ok
+}
+
+// tryAcquireOrWait take n tokens like tryAcquire, or return the channel which is closed when tokens may become
+// available. the channel is taken under the same lock, so a release right after the try is not missed
+func (s *semaphore) tryAcquireOrWait(n int) (<-chan struct{}, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.used+n > s.size+s.boost {
+		return s.released, false
+	}
+	s.used += n
+	return nil, true
+}
+
+// reserve take n tokens at once even if they are in use, the workers over the limit exit after their tasks
+func (s *semaphore) reserve(n int) {
+	s.mu.Lock()
+	s.used += n
+	s.notify(&s.resized)
+	s.mu.Unlock()
+}
+
+// acquire block until n tokens are taken, and report false if done is closed before that
+func (s *semaphore) acquire(n int, done <-chan struct{}) bool {
+	for {
+		released, ok := s.tryAcquireOrWait(n)
+		if ok {
+			return true
+		}
+		select {
+		case <-released:
+		case <-done:
+			return false
+		}
+	}
+}
+
+func (s *semaphore) release(n int) {
+	s.mu.Lock()
+	s.used -= n
+	s.notify(&s.released)
+	s.mu.Unlock()
+}
+
+// releaseOver release a token if the used tokens exceed the limit, and report whether it's released.
+// if not, it returns the channel which is closed when the limit changes
+func (s *semaphore) releaseOver() (<-chan struct{}, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.used <= s.size+s.boost {
+		return s.resized, false
+	}
+	s.used--
+	return nil, true
+}
+
+func (s *semaphore) resize(size int) {
+	s.mu.Lock()
+	s.size = size
+	s.notify(&s.released)
+	s.notify(&s.resized)
+	s.mu.Unlock()
+}
+
+func (s *semaphore) grow(boost int) {
+	s.mu.Lock()
+	s.boost += boost
+	s.notify(&s.released)
+	s.notify(&s.resized)
+	s.mu.Unlock()
+}
+
+// wait return a channel which is closed when tokens may become available
+func (s *semaphore) wait() <-chan struct{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.released
+}
+
+func (s *semaphore) notify(ch *chan struct{}) {
+	close(*ch)
+	*ch = make(chan struct{})
+}
@@ -0,0 +1,33 @@
+package groutine_pool
+
+import (
+	"context"
+	"sync"
+)
+
+// Stream submit tasks to the pool, and send their results to the returned channel in completion order.
+// the channel is closed when all tasks are finished. a panicked task sends nothing,
+// and the panic is handled by the pool's recover policy
+func Stream[T any](p *Pool, tasks []func(ctx context.Context) T) <-chan T {
+	results := make(chan T, len(tasks))
+
+	var wg sync.WaitGroup
+	wg.Add(len(tasks))
+	go func() {
+		for _, task := range tasks {
+			task := task
+			err := p.submit(context.Background(), func(ctx context.Context) {
+				defer wg.Done()
+				defer p.doRecover()
+				results <- task(ctx)
+			}, wg.Done)
+			if err != nil {
+				wg.Done()
+			}
+		}
+		wg.Wait()
+		close(results)
+	}()
+
+	return results
+}
@@ -0,0 +1,66 @@
+package groutine_pool
+
+import (
+	"errors"
+	"sync"
+)
+
+// subPools are the children of a pool created by SubPool
+type subPools struct {
+	mu    sync.Mutex
+	pools map[string]*Pool
+}
+
+// SubPool return the child pool of name with its own concurrency, creating it if not exists.
+// the child inherits the configuration and the ctx of the parent, and is closed when the parent is closed,
+// so a noisy workload in one child can't starve the others (bulkhead)
+func (g *Pool) SubPool(name string, concurrency int) *Pool {
+	g.children.mu.Lock()
+	defer g.children.mu.Unlock()
+	if child, ok := g.children.pools[name]; ok {
+		return child
+	}
+
+	child := NewPool(
+		WithCtx(g.ctx),
+		WithConcurrent(concurrency),
+		WithIdleTimeout(g.idleTimeout),
+		WithTaskDeadline(g.taskDeadline),
+		WithQueueSize(g.queueSize),
+		WithAdaptiveBackpressure(g.softLimit, g.maxDelay),
+		WithRecover(g.recoverFunc),
+		WithRecoverStack(g.recoverStack),
+		WithPanicStackDepth(g.stackDepth),
+		WithRecoverScope(g.recoverScope),
+		WithSpawner(g.spawner),
+		WithClock(g.clock),
+		WithClosedTaskHandler(g.closedTaskHandler),
+		WithTaskHook(g.onTaskStart, g.onTaskEnd),
+		WithSlowTaskThreshold(g.slowThreshold, g.onSlow),
+	)
+	if g.children.pools == nil {
+		g.children.pools = map[string]*Pool{}
+	}
+	g.children.pools[name] = child
+
+	g.RLock()
+	closed := g.closed
+	g.RUnlock()
+	if closed {
+		child.Close(true)
+	}
+	return child
+}
+
+// closeChildren close the children created by SubPool, and return the joined error of them
+func (g *Pool) closeChildren(grace bool) error {
+	g.children.mu.Lock()
+	defer g.children.mu.Unlock()
+	var errs []error
+	for _, child := range g.children.pools {
+		if _, err := child.Close(grace); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
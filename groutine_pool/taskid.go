@@ -0,0 +1,61 @@
+package groutine_pool
+
+import (
+	"context"
+	"sync"
+)
+
+// TaskID identifies a task submitted by ExecuteID, see Await
+type TaskID uint64
+
+// taskIDs tracks the unfinished tasks submitted by ExecuteID
+type taskIDs struct {
+	mu    sync.Mutex
+	last  TaskID
+	tasks map[TaskID]chan struct{} // closed when the task finishes
+}
+
+// ExecuteID submit a task to the pool like Execute, and return its id for Await
+func (g *Pool) ExecuteID(f func(context.Context)) TaskID {
+	done := make(chan struct{})
+	g.ids.mu.Lock()
+	if g.ids.tasks == nil {
+		g.ids.tasks = map[TaskID]chan struct{}{}
+	}
+	g.ids.last++
+	id := g.ids.last
+	g.ids.tasks[id] = done
+	g.ids.mu.Unlock()
+
+	finish := func() {
+		g.ids.mu.Lock()
+		delete(g.ids.tasks, id)
+		g.ids.mu.Unlock()
+		close(done)
+	}
+	if f == nil {
+		finish()
+		return id
+	}
+	err := g.submit(context.Background(), func(ctx context.Context) {
+		defer finish()
+		f(ctx)
+	}, finish)
+	if err != nil {
+		finish()
+	}
+	return id
+}
+
+// Await block until the tasks of ids finish, ids of finished or rejected tasks return at once.
+// unlike WaitAll, it doesn't wait for other tasks
+func (g *Pool) Await(ids ...TaskID) {
+	for _, id := range ids {
+		g.ids.mu.Lock()
+		done, ok := g.ids.tasks[id]
+		g.ids.mu.Unlock()
+		if ok {
+			<-done
+		}
+	}
+}
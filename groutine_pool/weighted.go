@@ -0,0 +1,86 @@
+package groutine_pool
+
+import "context"
+
+// ExecuteWeighted submit a task counting as weight workers against the pool concurrent, like a heavy task
+// occupying three slots. it blocks until the slots are free, and returns ErrExceedConcurrent if weight
+// exceeds the concurrent, or the submission error like Submit.
+// all the slots are taken at once and the task runs on a new worker, so weighted tasks waiting for slots
+// never hold part of them
+func (g *Pool) ExecuteWeighted(weight int, f func(context.Context)) error {
+	if weight <= 1 {
+		return g.Submit(f)
+	}
+	if f == nil {
+		return ErrNilTask
+	}
+
+	var size int64
+	if g.memory != nil {
+		size = g.memory.sizeOf(f)
+		if err := g.memory.acquire(context.Background(), g.closing, size); err != nil {
+			return g.rejectClosed(f)
+		}
+	}
+	releaseMemory := func() {
+		if g.memory != nil {
+			g.memory.release(size)
+		}
+	}
+
+	for {
+		released, ok := g.tokens.tryAcquireOrWait(weight)
+		if ok {
+			break
+		}
+		if weight > g.tokens.limit() { // checked on each try, the limit may shrink while waiting
+			releaseMemory()
+			return ErrExceedConcurrent
+		}
+		select {
+		case <-released: // also closed when the limit changes
+		case <-g.closing:
+			releaseMemory()
+			return g.rejectClosed(f)
+		}
+	}
+
+	g.RLock()
+	if g.closed {
+		g.RUnlock()
+		g.tokens.release(weight)
+		releaseMemory()
+		return g.rejectClosed(f)
+	}
+	task, _ := g.track(f, size, nil)
+	g.spawnWeighted(weight, task)
+	g.RUnlock()
+	return nil
+}
+
+// spawnWeighted start a worker holding weight tokens for the task. it exits after the task instead of
+// waiting for queued tasks, so the tokens are not kept by an idle worker
+func (g *Pool) spawnWeighted(weight int, task func(context.Context)) {
+	g.acquired()
+	g.wait.Add(1)
+	g.spawner(func() {
+		defer g.wait.Done()
+		w := g.registry.add()
+		defer g.registry.remove(w)
+		defer func() {
+			g.workers.Add(-1)
+			g.tokens.release(weight)
+			g.startQueued()
+		}()
+		_ = g.run(w, task)
+	})
+}
+
+// rejectClosed hand a task submitted after close to the closed task handler, or reject it
+func (g *Pool) rejectClosed(f func(context.Context)) error {
+	err := g.submitClosed(f)
+	if err != nil {
+		g.rejected.Add(1)
+	}
+	return err
+}
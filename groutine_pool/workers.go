@@ -0,0 +1,108 @@
+package groutine_pool
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// WorkerState is the state of a worker reported by DumpWorkers
+type WorkerState struct {
+	ID      int64
+	Busy    bool          // running a task, otherwise waiting for tasks
+	Label   string        // label of the running task submitted by ExecuteLabeled
+	Running time.Duration // how long the running task has been running
+}
+
+// workerRegistry tracks the running workers for DumpWorkers
+type workerRegistry struct {
+	mu      sync.Mutex
+	nextID  int64
+	workers map[int64]*worker
+}
+
+type worker struct {
+	id  int64
+	ctx context.Context // ctx of the tasks, with the worker as the value of workerKey
+
+	mu    sync.Mutex
+	label string
+	start time.Time // zero when idle
+}
+
+type workerKey struct{}
+
+func (r *workerRegistry) add() *worker {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.workers == nil {
+		r.workers = map[int64]*worker{}
+	}
+	r.nextID++
+	w := &worker{id: r.nextID}
+	r.workers[w.id] = w
+	return w
+}
+
+func (r *workerRegistry) remove(w *worker) {
+	r.mu.Lock()
+	delete(r.workers, w.id)
+	r.mu.Unlock()
+}
+
+func (w *worker) begin(now time.Time) {
+	w.mu.Lock()
+	w.start, w.label = now, ""
+	w.mu.Unlock()
+}
+
+func (w *worker) end() {
+	w.mu.Lock()
+	w.start, w.label = time.Time{}, ""
+	w.mu.Unlock()
+}
+
+// setLabel set the label of the task running with ctx
+func setLabel(ctx context.Context, label string) {
+	if w, ok := ctx.Value(workerKey{}).(*worker); ok {
+		w.mu.Lock()
+		w.label = label
+		w.mu.Unlock()
+	}
+}
+
+// DumpWorkers return the state of each running worker ordered by ID, for diagnosing a stuck pool
+func (g *Pool) DumpWorkers() []WorkerState {
+	g.registry.mu.Lock()
+	workers := make([]*worker, 0, len(g.registry.workers))
+	for _, w := range g.registry.workers {
+		workers = append(workers, w)
+	}
+	g.registry.mu.Unlock()
+
+	now := g.clock.Now()
+	states := make([]WorkerState, 0, len(workers))
+	for _, w := range workers {
+		w.mu.Lock()
+		state := WorkerState{ID: w.id, Busy: !w.start.IsZero(), Label: w.label}
+		if state.Busy {
+			state.Running = now.Sub(w.start)
+		}
+		w.mu.Unlock()
+		states = append(states, state)
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].ID < states[j].ID })
+	return states
+}
+
+// Healthy report whether no worker has been running a single task longer than maxTaskDuration,
+// like a liveness probe restarting the process whose pool is stuck by deadlocked tasks
+func (g *Pool) Healthy(maxTaskDuration time.Duration) bool {
+	for _, state := range g.DumpWorkers() {
+		if state.Busy && state.Running > maxTaskDuration {
+			return false
+		}
+	}
+	return true
+}
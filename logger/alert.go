@@ -0,0 +1,91 @@
+package logger
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/exp/slog"
+)
+
+// Record is a log record passed to callbacks like WithAlert
+type Record struct {
+	Time    time.Time
+	Level   LogLevel
+	Message string
+	Attrs   []slog.Attr // attributes of the logger and the record
+}
+
+type alert struct {
+	predicate func(level LogLevel, msg string, attrs []slog.Attr) bool
+	fn        func(Record)
+}
+
+// alertQueueSize is the number of matched records waiting for alert callbacks, overflow is dropped
+const alertQueueSize = 256
+
+// alerter runs alert callbacks in its own goroutine, so slow callbacks don't block logging
+type alerter struct {
+	queue chan alertCall
+	done  chan struct{}
+}
+
+type alertCall struct {
+	fn     func(Record)
+	record Record
+}
+
+// alertsDropped counts the alert calls dropped because the queue is full
+var alertsDropped atomic.Uint64
+
+// AlertsDropped return the number of alert calls dropped because callbacks couldn't keep up
+func AlertsDropped() uint64 {
+	return alertsDropped.Load()
+}
+
+// alerts is the alerter of the default logger set by Init
+var alerts struct {
+	sync.Mutex
+	alerter *alerter
+}
+
+func newAlerter() *alerter {
+	a := &alerter{queue: make(chan alertCall, alertQueueSize), done: make(chan struct{})}
+	go a.run()
+	return a
+}
+
+func (a *alerter) run() {
+	for {
+		select {
+		case call := <-a.queue:
+			a.call(call)
+		case <-a.done:
+			return
+		}
+	}
+}
+
+// call run the callback, a panicking callback doesn't stop the alerter
+func (a *alerter) call(call alertCall) {
+	defer func() { _ = recover() }()
+	call.fn(call.record)
+}
+
+func (a *alerter) send(call alertCall) {
+	select {
+	case a.queue <- call:
+	default:
+		alertsDropped.Add(1)
+	}
+}
+
+// setAlerter replace the alerter of the default logger, stopping the previous one
+func setAlerter(a *alerter) {
+	alerts.Lock()
+	defer alerts.Unlock()
+	if alerts.alerter != nil {
+		close(alerts.alerter.done)
+	}
+	alerts.alerter = a
+}
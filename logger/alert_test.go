@@ -0,0 +1,41 @@
+package logger
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"golang.org/x/exp/slog"
+)
+
+func TestAlertBelowLevel(t *testing.T) {
+	defer Init()
+	var buf bytes.Buffer
+	alerted := make(chan Record, 1)
+	Init(WithWriter(&buf), WithSequence(), WithSummaryOnClose(), WithAlert(func(level LogLevel, msg string, attrs []slog.Attr) bool {
+		for _, a := range attrs {
+			if a.Key == "severity" && a.Value.String() == "critical" {
+				return true
+			}
+		}
+		return false
+	}, func(r Record) { alerted <- r }))
+
+	Debug("x", "severity", "critical")
+	select {
+	case r := <-alerted:
+		if r.Message != "x" || r.Level != LevelDebug {
+			t.Fatalf("alert record %+v", r)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no alert for the debug record")
+	}
+	Info("kept")
+	if err := Close(); err != nil {
+		t.Fatal(err)
+	}
+	if out := buf.String(); !bytes.Contains(buf.Bytes(), []byte("seq=1")) || bytes.Contains(buf.Bytes(), []byte("msg=x")) ||
+		!bytes.Contains(buf.Bytes(), []byte("debug=0")) {
+		t.Fatalf("the debug record was written or counted:\n%s", out)
+	}
+}
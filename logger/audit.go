@@ -0,0 +1,47 @@
+package logger
+
+import (
+	"context"
+	"io"
+	"runtime"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/exp/slog"
+)
+
+// auditSeq is the sequence number of audit records, it increases across Init
+var auditSeq atomic.Uint64
+
+type auditKey struct{}
+
+func isAudit(ctx context.Context) bool {
+	return ctx.Value(auditKey{}) != nil
+}
+
+// WithAuditOutput add an output receiving audit records written by Audit in json format.
+// audit records are not written to the other outputs, and the audit output receives nothing else
+func WithAuditOutput(w io.Writer) Option {
+	return func(o *option) {
+		o.outputs = append(o.outputs, output{writer: w, format: FormatJSON, audit: true})
+	}
+}
+
+// Audit write an audit record with message "audit" and attributes action, actor, target, audit_seq,
+// and attrs in group "details". it's written to the outputs added by WithAuditOutput, or all the outputs
+// if there is none. audit records are never dropped by level, filters or rate limit
+func Audit(ctx context.Context, action string, actor string, target string, attrs ...any) {
+	var pcs [1]uintptr
+	runtime.Callers(2, pcs[:]) // skip [runtime.Callers, Audit]
+	r := slog.NewRecord(time.Now(), slog.LevelInfo, "audit", pcs[0])
+	r.AddAttrs(
+		slog.String("action", action),
+		slog.String("actor", actor),
+		slog.String("target", target),
+		slog.Uint64("audit_seq", auditSeq.Add(1)),
+	)
+	if len(attrs) > 0 {
+		r.AddAttrs(slog.Group("details", attrs...))
+	}
+	_ = slog.Default().Handler().Handle(context.WithValue(ctx, auditKey{}, true), r)
+}
@@ -0,0 +1,32 @@
+package logger
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestAuditOutputDoesNotEnableDebug(t *testing.T) {
+	defer Init()
+	var buf, audit bytes.Buffer
+	Init(WithWriter(&buf), WithAuditOutput(&audit), WithSequence(), WithSummaryOnClose())
+	if Event(LevelDebug) != nil {
+		t.Fatal("Event(LevelDebug) is enabled by the audit output")
+	}
+	Debug("dropped")
+	Info("kept")
+	if err := Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if !strings.Contains(lines[0], "seq=1") {
+		t.Errorf("first record %q, want seq=1", lines[0])
+	}
+	if summary := lines[len(lines)-1]; !strings.Contains(summary, "debug=0") {
+		t.Errorf("summary %q, want debug=0", summary)
+	}
+	if audit.Len() != 0 {
+		t.Errorf("audit output got %q, want nothing", audit.String())
+	}
+}
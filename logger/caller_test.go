@@ -0,0 +1,43 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestCallerSource(t *testing.T) {
+	defer Init()
+	var buf bytes.Buffer
+	Init(WithWriter(&buf), WithSource(), WithFunction(), WithErrorChain())
+
+	cases := []struct {
+		name string
+		log  func()
+	}{
+		{"LogRequest", func() {
+			err := fmt.Errorf("get: %w", errors.New("refused"))
+			LogRequest(context.Background(), "GET", "http://x", 0, 0, err)
+		}},
+		{"StdLogger", func() { StdLogger(LevelInfo).Print("std") }},
+		{"Writer", func() { _, _ = Writer(LevelInfo).Write([]byte("w\n")) }},
+	}
+	for _, c := range cases {
+		buf.Reset()
+		c.log()
+		line := buf.String()
+		if !strings.Contains(line, "caller_test.go:") || !strings.Contains(line, "func=logger.TestCallerSource") {
+			t.Errorf("%s: source isn't the caller: %s", c.name, line)
+		}
+	}
+
+	buf.Reset()
+	Init(WithWriter(&buf), WithErrorChain(), JSONOutput())
+	LogRequest(context.Background(), "GET", "http://x", 0, 0, fmt.Errorf("get: %w", errors.New("refused")))
+	if !strings.Contains(buf.String(), "*errors.errorString") {
+		t.Errorf("error isn't logged as a chain: %s", buf.String())
+	}
+}
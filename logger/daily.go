@@ -0,0 +1,60 @@
+package logger
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// dailyFile is a writer writing to dir/prefix-YYYY-MM-DD.log, it switches files on write when the date changes
+type dailyFile struct {
+	dir    string
+	prefix string
+	loc    *time.Location
+
+	mu   sync.Mutex
+	date string
+	file *os.File
+}
+
+func newDailyFile(dir, prefix string, loc *time.Location) *dailyFile {
+	return &dailyFile{dir: dir, prefix: prefix, loc: loc}
+}
+
+func (d *dailyFile) Write(p []byte) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if date := time.Now().In(d.loc).Format("2006-01-02"); date != d.date || d.file == nil {
+		if err := d.open(date); err != nil {
+			return 0, err
+		}
+	}
+	return d.file.Write(p)
+}
+
+func (d *dailyFile) open(date string) error {
+	if err := os.MkdirAll(d.dir, 0o755); err != nil {
+		return err
+	}
+	name := filepath.Join(d.dir, d.prefix+"-"+date+".log")
+	file, err := os.OpenFile(name, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	if d.file != nil {
+		_ = d.file.Close()
+	}
+	d.file, d.date = file, date
+	return nil
+}
+
+func (d *dailyFile) Sync() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.file == nil {
+		return nil
+	}
+	return d.file.Sync()
+}
@@ -0,0 +1,30 @@
+package logger
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestDynamicAttrPanicWarns(t *testing.T) {
+	defer Init()
+	var buf bytes.Buffer
+	Init(WithWriter(&buf), WithDynamicAttr("color", func() any { panic("no color") }))
+	Info("m")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want the warning and the record:\n%s", len(lines), buf.String())
+	}
+	for _, want := range []string{"level=WARN", `msg="dynamic attribute panic"`, "key=color", `panic="no color"`} {
+		if !strings.Contains(lines[0], want) {
+			t.Errorf("warning %q doesn't contain %q", lines[0], want)
+		}
+	}
+	if strings.Contains(lines[0], "PANIC") {
+		t.Errorf("the warning %q has the dynamic attribute", lines[0])
+	}
+	if !strings.Contains(lines[1], `msg=m color="!PANIC: no color"`) {
+		t.Errorf("record %q, want the !PANIC value", lines[1])
+	}
+}
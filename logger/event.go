@@ -0,0 +1,127 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+	"time"
+
+	"golang.org/x/exp/slog"
+)
+
+// EventBuilder builds a record with chained typed setters, like
+// `logger.Event(LevelInfo).Str("user", u).Int("count", n).Msg("done")`.
+// the builder is nil if the level is disabled, and all its methods are no-ops then.
+// a builder must not be used after Msg or Msgf
+type EventBuilder struct {
+	ctx   context.Context
+	level slog.Level
+	log   bool // false for a disabled panic event, which only panics
+	attrs []slog.Attr
+}
+
+var eventPool = sync.Pool{New: func() any { return &EventBuilder{attrs: make([]slog.Attr, 0, 8)} }}
+
+// Event start a record at level with the default logger, it returns nil if the level is disabled
+func Event(level LogLevel) *EventBuilder {
+	l := slog.Default()
+	sLevel := levelMap[level]
+	enabled := l.Enabled(context.Background(), sLevel)
+	if !enabled && level < LevelPanic { // panic and fatal events always panic or exit
+		return nil
+	}
+
+	e := eventPool.Get().(*EventBuilder)
+	e.ctx = context.Background()
+	e.level = sLevel
+	e.log = enabled
+	return e
+}
+
+// Ctx set the ctx passed to the handler, for the context extractors
+func (e *EventBuilder) Ctx(ctx context.Context) *EventBuilder {
+	if e != nil {
+		e.ctx = ctx
+	}
+	return e
+}
+
+func (e *EventBuilder) Str(key, value string) *EventBuilder {
+	if e != nil {
+		e.attrs = append(e.attrs, slog.String(key, value))
+	}
+	return e
+}
+
+func (e *EventBuilder) Int(key string, value int) *EventBuilder {
+	if e != nil {
+		e.attrs = append(e.attrs, slog.Int(key, value))
+	}
+	return e
+}
+
+func (e *EventBuilder) Bool(key string, value bool) *EventBuilder {
+	if e != nil {
+		e.attrs = append(e.attrs, slog.Bool(key, value))
+	}
+	return e
+}
+
+func (e *EventBuilder) Dur(key string, value time.Duration) *EventBuilder {
+	if e != nil {
+		e.attrs = append(e.attrs, slog.Duration(key, value))
+	}
+	return e
+}
+
+// Err add err with key "error" like LogErr, nil err is skipped
+func (e *EventBuilder) Err(err error) *EventBuilder {
+	if e != nil && err != nil {
+		e.attrs = append(e.attrs, slog.Any("error", err))
+	}
+	return e
+}
+
+func (e *EventBuilder) Any(key string, value any) *EventBuilder {
+	if e != nil {
+		e.attrs = append(e.attrs, slog.Any(key, value))
+	}
+	return e
+}
+
+// Msg emit the record with msg. a panic event panics with msg after logging
+func (e *EventBuilder) Msg(msg string) {
+	if e != nil {
+		e.emit(msg)
+	}
+}
+
+// Msgf emit the record with the formatted message
+func (e *EventBuilder) Msgf(format string, v ...any) {
+	if e != nil {
+		e.emit(fmt.Sprintf(format, v...))
+	}
+}
+
+// emit must be called directly by Msg or Msgf, since it uses a fixed call depth like logAt
+func (e *EventBuilder) emit(msg string) {
+	if e.log {
+		var pcs [1]uintptr
+		runtime.Callers(3, pcs[:]) // skip [runtime.Callers, emit, Msg or Msgf]
+		r := slog.NewRecord(time.Now(), e.level, msg, pcs[0])
+		r.AddAttrs(e.attrs...)
+		_ = slog.Default().Handler().Handle(e.ctx, r)
+	}
+
+	level := e.level
+	e.ctx = nil
+	e.attrs = e.attrs[:0]
+	eventPool.Put(e)
+	switch level {
+	case slogLevelPanic:
+		doPanic(msg)
+	case slogLevelFatal:
+		exit(1)
+	}
+}
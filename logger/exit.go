@@ -0,0 +1,59 @@
+package logger
+
+import (
+	"os"
+	"sync"
+)
+
+// WithExitFunc set the function called by Fatal to exit the process after flushing, default is os.Exit.
+// tests can record the code instead of exiting, then Fatal returns like other logging functions
+func WithExitFunc(fn func(code int)) Option {
+	return func(o *option) {
+		o.exitFunc = fn
+	}
+}
+
+// WithPanicFunc set the function called by Panic with the panic message after logging, default is panic.
+// tests can record the value instead of panicking, then Panic returns like other logging functions
+func WithPanicFunc(fn func(v any)) Option {
+	return func(o *option) {
+		o.panicFunc = fn
+	}
+}
+
+// terminate is the exit and panic functions of the default logger set by Init
+var terminate struct {
+	sync.Mutex
+	exit  func(code int)
+	panic func(v any)
+}
+
+func setTerminate(exit func(code int), panicFunc func(v any)) {
+	if exit == nil {
+		exit = os.Exit
+	}
+	if panicFunc == nil {
+		panicFunc = func(v any) { panic(v) }
+	}
+	terminate.Lock()
+	terminate.exit, terminate.panic = exit, panicFunc
+	terminate.Unlock()
+}
+
+// exit flush buffered records and call the exit function
+func exit(code int) {
+	_ = Sync()
+	terminate.Lock()
+	fn := terminate.exit
+	terminate.Unlock()
+	fn(code)
+}
+
+// doPanic flush buffered records and call the panic function, an unrecovered panic exits without flushing
+func doPanic(v any) {
+	_ = Sync()
+	terminate.Lock()
+	fn := terminate.panic
+	terminate.Unlock()
+	fn(v)
+}
@@ -0,0 +1,23 @@
+package logger
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestPanicFlushesBuffer(t *testing.T) {
+	defer Init()
+	var buf bytes.Buffer
+	var panicked any
+	Init(WithWriter(&buf), WithBuffer(4096), WithPanicFunc(func(v any) {
+		panicked = v
+		if !strings.Contains(buf.String(), "msg=boom") {
+			t.Errorf("the panic record is still buffered when panicking, output %q", buf.String())
+		}
+	}))
+	Panic("boom")
+	if panicked == nil {
+		t.Fatal("Panic didn't call the panic function")
+	}
+}
@@ -0,0 +1,45 @@
+package logger
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// appendFile is a writer appending to the file at path, the file is opened on the first write
+type appendFile struct {
+	path string
+
+	mu   sync.Mutex
+	file *os.File
+}
+
+func newAppendFile(path string) *appendFile {
+	return &appendFile{path: path}
+}
+
+func (f *appendFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		if err := os.MkdirAll(filepath.Dir(f.path), 0o755); err != nil {
+			return 0, err
+		}
+		file, err := os.OpenFile(f.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+		if err != nil {
+			return 0, err
+		}
+		f.file = file
+	}
+	return f.file.Write(p)
+}
+
+func (f *appendFile) Sync() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.file == nil {
+		return nil
+	}
+	return f.file.Sync()
+}
@@ -0,0 +1,33 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"runtime"
+	"strconv"
+
+	"golang.org/x/exp/slog"
+)
+
+// WithGoroutineID add the id of the logging goroutine as attribute "goroutine", for debugging concurrency.
+// go doesn't expose goroutine ids, so it's parsed from the header of runtime.Stack, "goroutine 42 [running]:",
+// which costs a stack capture per record. it's meant for debugging builds
+func WithGoroutineID() Option {
+	return WithExtractor(func(context.Context) []slog.Attr {
+		if id, ok := goroutineID(); ok {
+			return []slog.Attr{slog.Uint64("goroutine", id)}
+		}
+		return nil
+	})
+}
+
+func goroutineID() (uint64, bool) {
+	var buf [64]byte
+	b := buf[:runtime.Stack(buf[:], false)]
+	b = bytes.TrimPrefix(b, []byte("goroutine "))
+	if i := bytes.IndexByte(b, ' '); i > 0 {
+		b = b[:i]
+	}
+	id, err := strconv.ParseUint(string(b), 10, 64)
+	return id, err == nil
+}
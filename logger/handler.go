@@ -0,0 +1,392 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"strings"
+	"sync/atomic"
+
+	"golang.org/x/exp/slog"
+)
+
+// handler wraps the slog handlers built from options, and applies the per record features of the logger
+type handler struct {
+	sinks       []sink
+	attrs       []slog.Attr // attributes added by WithAttrs, passed to predicates of sinks
+	gated       []slog.Attr // level gated attributes added by WithAttrs
+	extractors  []func(ctx context.Context) []slog.Attr
+	filters     []func(level LogLevel, msg string, attrs []slog.Attr) bool
+	limiter     *rateLimiter // shared by handlers derived from the same logger
+	alerts      []alert
+	tees        []tee
+	alerter     *alerter
+	hasAudit    bool           // audit records go to audit sinks only
+	hasJSON     bool           // records logged with AsJSON go to the json sink instead of the primary sink
+	function    bool           // add the caller's function name
+	sourceLevel slog.Level     // min level of records with source
+	trackError  bool           // set hadErrors by error and panic records
+	keyPrefix   string         // prepended to top level group names, other keys are prefixed by replaceAttr
+	grouped     bool           // a group is opened by WithGroup, so attributes are not top level
+	seq         *atomic.Uint64 // sequence number of records, shared by handlers derived from the same logger
+	counts      *levelCounts   // records per level for WithSummaryOnClose
+}
+
+// hadErrors is set when an error or panic record is handled with WithErrorTracking
+var hadErrors atomic.Bool
+
+// HadErrors report whether any error or panic record was logged with WithErrorTracking,
+// like deciding the exit code of a CLI tool
+func HadErrors() bool {
+	return hadErrors.Load()
+}
+
+// sink is an output of the logger
+type sink struct {
+	slog.Handler
+	predicate func(level LogLevel, attrs []slog.Attr) bool // nil means all records
+	audit     bool                                         // only receives audit records
+	primary   bool                                         // the writer of the logger
+	json      bool                                         // the writer of the logger in json format, only receives records logged with AsJSON
+}
+
+func (h *handler) Enabled(ctx context.Context, level slog.Level) bool {
+	if len(h.alerts) > 0 { // alerts see the records below the level too
+		return true
+	}
+	return h.sinksEnabled(ctx, level)
+}
+
+// sinksEnabled report whether any sink writes records of the level
+func (h *handler) sinksEnabled(ctx context.Context, level slog.Level) bool {
+	for _, s := range h.sinks {
+		if !s.audit && s.Enabled(ctx, level) { // audit records are handled by Audit without Enabled
+			return true
+		}
+	}
+	return false
+}
+
+func (h *handler) Handle(ctx context.Context, r slog.Record) error {
+	if isAudit(ctx) {
+		return h.handleAudit(ctx, r)
+	}
+	forced := isSummary(ctx) // not dropped by level, filters or rate limit, and not counted
+	if len(h.alerts) > 0 {
+		h.alert(r)
+		if !forced && !h.sinksEnabled(ctx, r.Level) { // only enabled for the alerts
+			return nil
+		}
+	}
+	if len(h.filters) > 0 && !forced && !h.filter(r) {
+		return nil
+	}
+	if h.limiter != nil && !forced {
+		ok, dropped := h.limiter.allow(r.Message, r.Time)
+		if !ok {
+			return nil
+		}
+		if dropped > 0 {
+			r = r.Clone()
+			r.AddAttrs(slog.Int("dropped", dropped))
+		}
+	}
+	if h.counts != nil && !forced {
+		h.counts.add(r.Level)
+	}
+	if h.trackError && r.Level >= slog.LevelError {
+		hadErrors.Store(true)
+	}
+	if h.hasGated(r) {
+		r = h.gate(r)
+	}
+	r = h.extract(ctx, r)
+	if h.seq != nil {
+		r = r.Clone()
+		r.AddAttrs(slog.Uint64("seq", h.seq.Add(1)))
+	}
+	if r.Level < h.sourceLevel {
+		r.PC = 0 // source and function are dropped
+	}
+	if h.function {
+		if name := funcName(r.PC); name != "" {
+			r = r.Clone()
+			r.AddAttrs(slog.String("func", name))
+		}
+	}
+
+	asJSON := h.hasJSON && isJSON(ctx)
+	var attrs []slog.Attr // collected on demand for predicates and tees
+	if len(h.tees) > 0 {
+		attrs = h.collect(r)
+		for _, t := range h.tees {
+			t.send(Record{Time: r.Time, Level: toLogLevel(r.Level), Message: r.Message, Attrs: attrs})
+		}
+	}
+	out := h.prefixGroups(r)
+	var errs []error
+	for _, s := range h.sinks {
+		if s.audit || !forced && !s.Enabled(ctx, r.Level) {
+			continue
+		}
+		if s.json != asJSON && (s.json || s.primary) { // the writer gets AsJSON records from the json sink only
+			continue
+		}
+		if s.predicate != nil {
+			if attrs == nil {
+				attrs = h.collect(r)
+			}
+			if !s.predicate(toLogLevel(r.Level), attrs) {
+				continue
+			}
+		}
+		if err := s.Handle(ctx, out); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// handleAudit write an audit record to the audit outputs, or all the outputs without audit outputs.
+// audit records are never dropped by level, filters, rate limit or predicates
+func (h *handler) handleAudit(ctx context.Context, r slog.Record) error {
+	r = h.prefixGroups(h.extract(ctx, r))
+
+	var errs []error
+	for _, s := range h.sinks {
+		if s.audit == h.hasAudit && !s.json {
+			if err := s.Handle(ctx, r); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// extract add the request id and the attributes of the extractors from ctx to the record
+func (h *handler) extract(ctx context.Context, r slog.Record) slog.Record {
+	id := RequestID(ctx)
+	extractors := h.extractors
+	if ctx.Value(internalKey{}) != nil {
+		extractors = nil
+	}
+	if id == "" && len(extractors) == 0 {
+		return r
+	}
+	r = r.Clone()
+	if id != "" {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	for _, extract := range extractors {
+		r.AddAttrs(extract(ctx)...)
+	}
+	return r
+}
+
+// alert send the record to the callbacks of the matching alerts, a panicking predicate doesn't match
+func (h *handler) alert(r slog.Record) {
+	level, attrs := toLogLevel(r.Level), h.collect(r)
+	for _, a := range h.alerts {
+		if matchAlert(a, level, r.Message, attrs) {
+			h.alerter.send(alertCall{fn: a.fn, record: Record{Time: r.Time, Level: level, Message: r.Message, Attrs: attrs}})
+		}
+	}
+}
+
+func matchAlert(a alert, level LogLevel, msg string, attrs []slog.Attr) (match bool) {
+	defer func() {
+		if recover() != nil {
+			match = false
+		}
+	}()
+	return a.predicate(level, msg, attrs)
+}
+
+// filter report whether the record passes all the filters, a panicking filter passes the record
+func (h *handler) filter(r slog.Record) (pass bool) {
+	defer func() {
+		if recover() != nil {
+			pass = true
+		}
+	}()
+	level, attrs := toLogLevel(r.Level), h.collect(r)
+	for _, fn := range h.filters {
+		if !fn(level, r.Message, attrs) {
+			return false
+		}
+	}
+	return true
+}
+
+// collect return attributes of the handler and the record
+func (h *handler) collect(r slog.Record) []slog.Attr {
+	attrs := make([]slog.Attr, 0, len(h.attrs)+r.NumAttrs())
+	attrs = append(attrs, h.attrs...)
+	r.Attrs(func(a slog.Attr) bool {
+		attrs = append(attrs, a)
+		return true
+	})
+	return attrs
+}
+
+// gate return a new record without the level gated attributes which should not be logged at the record level
+func (h *handler) gate(r slog.Record) slog.Record {
+	nr := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		if a, ok := gateAttr(r.Level, a); ok {
+			nr.AddAttrs(a)
+		}
+		return true
+	})
+	for _, a := range h.gated {
+		if a, ok := gateAttr(r.Level, a); ok {
+			nr.AddAttrs(a)
+		}
+	}
+	return nr
+}
+
+func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	h2 := *h
+	plain := make([]slog.Attr, 0, len(attrs))
+	for _, a := range attrs {
+		if isGated(a) {
+			h2.gated = append(h2.gated[:len(h2.gated):len(h2.gated)], a)
+		} else {
+			plain = append(plain, a)
+		}
+	}
+	h2.attrs = append(h2.attrs[:len(h2.attrs):len(h2.attrs)], plain...)
+	prefixed := plain
+	if h.keyPrefix != "" && !h.grouped {
+		prefixed = make([]slog.Attr, len(plain))
+		for i, a := range plain {
+			prefixed[i] = h.prefixGroup(a)
+		}
+	}
+	h2.sinks = make([]sink, len(h.sinks))
+	for i, s := range h.sinks {
+		s.Handler = s.Handler.WithAttrs(prefixed)
+		h2.sinks[i] = s
+	}
+	return &h2
+}
+
+func (h *handler) WithGroup(name string) slog.Handler {
+	h2 := *h
+	if name != "" {
+		if !h.grouped {
+			name = h.prefixKey(name)
+		}
+		h2.grouped = true
+	}
+	h2.sinks = make([]sink, len(h.sinks))
+	for i, s := range h.sinks {
+		s.Handler = s.Handler.WithGroup(name)
+		h2.sinks[i] = s
+	}
+	return &h2
+}
+
+// prefixGroups return the record with the key prefix on its top level groups, slog doesn't pass groups
+// to ReplaceAttr. predicates and tees get the keys without prefix like the other attributes
+func (h *handler) prefixGroups(r slog.Record) slog.Record {
+	if h.keyPrefix == "" || h.grouped {
+		return r
+	}
+	found := false
+	r.Attrs(func(a slog.Attr) bool {
+		found = a.Key != "" && a.Value.Kind() == slog.KindGroup
+		return !found
+	})
+	if !found {
+		return r
+	}
+	nr := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		nr.AddAttrs(h.prefixGroup(a))
+		return true
+	})
+	return nr
+}
+
+// prefixGroup prefix the key of a group attribute, its members are left alone
+func (h *handler) prefixGroup(a slog.Attr) slog.Attr {
+	if a.Key != "" && a.Value.Kind() == slog.KindGroup {
+		a.Key = h.prefixKey(a.Key)
+	}
+	return a
+}
+
+func (h *handler) prefixKey(key string) string {
+	if h.keyPrefix == "" || isBuiltinKey(key) || strings.HasPrefix(key, h.keyPrefix) {
+		return key
+	}
+	return h.keyPrefix + key
+}
+
+func (h *handler) hasGated(r slog.Record) bool {
+	if len(h.gated) > 0 {
+		return true
+	}
+	found := false
+	r.Attrs(func(a slog.Attr) bool {
+		found = isGated(a)
+		return !found
+	})
+	return found
+}
+
+// funcName return the function name of pc without the package path prefix, it's empty if pc can't be resolved
+func funcName(pc uintptr) string {
+	if pc == 0 {
+		return ""
+	}
+	frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	name := frame.Function
+	if i := strings.LastIndexByte(name, '/'); i >= 0 {
+		name = name[i+1:]
+	}
+	return name
+}
+
+// LevelGated return an attribute which is only logged when the record level is at least min.
+// level gated attributes added by With before WithGroup are logged inside the group
+func LevelGated(min LogLevel, attr slog.Attr) slog.Attr {
+	return slog.Any(attr.Key, levelGatedValue{min: levelMap[min], attr: attr})
+}
+
+type levelGatedValue struct {
+	min  slog.Level
+	attr slog.Attr
+}
+
+// LogValue is used when the attribute is not handled by this package's handler, like nested in a group
+func (v levelGatedValue) LogValue() slog.Value {
+	return v.attr.Value
+}
+
+func isGated(a slog.Attr) bool {
+	if a.Value.Kind() != slog.KindLogValuer {
+		return false
+	}
+	_, ok := a.Value.LogValuer().(levelGatedValue)
+	return ok
+}
+
+// gateAttr unwrap the level gated attribute, and report whether it should be logged at the level
+func gateAttr(level slog.Level, a slog.Attr) (slog.Attr, bool) {
+	if !isGated(a) {
+		return a, true
+	}
+	v := a.Value.LogValuer().(levelGatedValue)
+	return v.attr, level >= v.min
+}
+
+// discardHandler is a handler which is never enabled
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (h discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return h }
+func (h discardHandler) WithGroup(string) slog.Handler           { return h }
@@ -0,0 +1,82 @@
+package logger
+
+import (
+	"context"
+	"sync"
+
+	"golang.org/x/exp/slog"
+)
+
+type jsonKey struct{}
+
+// AsJSON return a ctx which makes records logged with it written in json format to the writer of the logger,
+// instead of the format of the logger, like `logger.InfoWithCtx(logger.AsJSON(ctx), "order", "id", id)`.
+// only the writer set by WithWriter is affected, the outputs added by WithOutput and WithFilteredOutput
+// receive the record in their own format and by their own predicates as usual
+func AsJSON(ctx context.Context) context.Context {
+	return context.WithValue(ctx, jsonKey{}, true)
+}
+
+func isJSON(ctx context.Context) bool {
+	return ctx.Value(jsonKey{}) != nil
+}
+
+// lazyHandler build its handler on the first record, so loggers never using AsJSON don't pay for
+// the json handler, and its WithAttrs and WithGroup are only replayed then
+type lazyHandler struct {
+	level slog.Leveler
+	build func() slog.Handler
+	built *built
+}
+
+type built struct {
+	once    sync.Once
+	handler slog.Handler
+}
+
+func newLazyHandler(level slog.Leveler, build func() slog.Handler) *lazyHandler {
+	return &lazyHandler{level: level, build: build, built: &built{}}
+}
+
+func (l *lazyHandler) get() slog.Handler {
+	l.built.once.Do(func() {
+		l.built.handler = l.build()
+	})
+	return l.built.handler
+}
+
+func (l *lazyHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= l.level.Level()
+}
+
+func (l *lazyHandler) Handle(ctx context.Context, r slog.Record) error {
+	return l.get().Handle(ctx, r)
+}
+
+func (l *lazyHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return newLazyHandler(l.level, func() slog.Handler {
+		return l.get().WithAttrs(attrs)
+	})
+}
+
+func (l *lazyHandler) WithGroup(name string) slog.Handler {
+	return newLazyHandler(l.level, func() slog.Handler {
+		return l.get().WithGroup(name)
+	})
+}
+
+func DebugJSON(ctx context.Context, msg string, args ...any) {
+	logAt(AsJSON(ctx), slog.LevelDebug, msg, args...)
+}
+
+func InfoJSON(ctx context.Context, msg string, args ...any) {
+	logAt(AsJSON(ctx), slog.LevelInfo, msg, args...)
+}
+
+func WarnJSON(ctx context.Context, msg string, args ...any) {
+	logAt(AsJSON(ctx), slog.LevelWarn, msg, args...)
+}
+
+func ErrorJSON(ctx context.Context, msg string, args ...any) {
+	logAt(AsJSON(ctx), slog.LevelError, msg, args...)
+}
@@ -0,0 +1,39 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"golang.org/x/exp/slog"
+)
+
+func TestAsJSONBuildsSinkLazily(t *testing.T) {
+	var buf bytes.Buffer
+	h := NewHandler(WithWriter(&buf)).(*handler)
+	var lazy *lazyHandler
+	for _, s := range h.sinks {
+		if s.json {
+			lazy = s.Handler.(*lazyHandler)
+		}
+	}
+	if lazy == nil {
+		t.Fatal("no json sink")
+	}
+
+	l := slog.New(h).With("a", 1).WithGroup("g").With("b", 2)
+	l.Info("text")
+	if lazy.built.handler != nil {
+		t.Fatal("json handler built without an AsJSON record")
+	}
+	l.InfoCtx(AsJSON(context.Background()), "json", "c", 3)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2:\n%s", len(lines), buf.String())
+	}
+	if want := `"msg":"json","a":1,"g":{"b":2,"c":3}}`; !strings.HasSuffix(lines[1], want) {
+		t.Errorf("json record %q, want suffix %q", lines[1], want)
+	}
+}
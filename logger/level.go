@@ -0,0 +1,80 @@
+package logger
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/exp/slog"
+)
+
+// loggerLevel is the level of the default logger. it's the last temporary level set by WithTemporaryLevel,
+// or the level set by Init and SetLevel without temporary levels
+var loggerLevel slog.LevelVar
+
+var levels struct {
+	sync.Mutex
+	base  slog.Level
+	temps []*slog.Level // temporary levels in the order they are set
+}
+
+// resetLevel set the level of Init and drop the temporary levels
+func resetLevel(level slog.Level) {
+	levels.Lock()
+	defer levels.Unlock()
+	levels.base, levels.temps = level, nil
+	loggerLevel.Set(level)
+}
+
+// SetLevel change the level of the default logger at runtime, it's safe with concurrent logging.
+// outputs added by WithOutput keep their own levels. while temporary levels set by WithTemporaryLevel
+// are active, the level takes effect after all of them are restored
+func SetLevel(level LogLevel) {
+	levels.Lock()
+	defer levels.Unlock()
+	levels.base = levelMap[level]
+	if len(levels.temps) == 0 {
+		loggerLevel.Set(levels.base)
+	}
+}
+
+// GetLevel return the level of the default logger
+func GetLevel() LogLevel {
+	return toLogLevel(loggerLevel.Level())
+}
+
+// SetLevelAfter change the level of the default logger to level after d, like quieting down once the service
+// is started. the returned stop cancels the change, it reports false if the change already happened
+func SetLevelAfter(level LogLevel, d time.Duration) (stop func() bool) {
+	return time.AfterFunc(d, func() { SetLevel(level) }).Stop
+}
+
+// WithTemporaryLevel set the level of the default logger until restore is called, like
+// `defer logger.WithTemporaryLevel(logger.LevelDebug)()`. temporary levels nest, the level is the last one
+// not restored yet, or the level set by SetLevel when all are restored, whatever order they are restored in.
+// calling restore more than once has no effect
+func WithTemporaryLevel(level LogLevel) (restore func()) {
+	temp := levelMap[level]
+	levels.Lock()
+	levels.temps = append(levels.temps, &temp)
+	loggerLevel.Set(temp)
+	levels.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			levels.Lock()
+			defer levels.Unlock()
+			for i, t := range levels.temps {
+				if t == &temp {
+					levels.temps = append(levels.temps[:i], levels.temps[i+1:]...)
+					break
+				}
+			}
+			if n := len(levels.temps); n > 0 {
+				loggerLevel.Set(*levels.temps[n-1])
+			} else {
+				loggerLevel.Set(levels.base)
+			}
+		})
+	}
+}
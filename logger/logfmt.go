@@ -0,0 +1,202 @@
+package logger
+
+import (
+	"context"
+	"encoding"
+	"fmt"
+	"io"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"golang.org/x/exp/slog"
+)
+
+// logfmtHandler is a slog.Handler which writes records in strict logfmt format.
+// built-in attributes come first (time, level, source, msg), followed by attributes in insertion order.
+// groups are flattened into dotted keys.
+type logfmtHandler struct {
+	opts   slog.HandlerOptions
+	w      io.Writer
+	mu     *sync.Mutex
+	prefix string   // key prefix of opened groups, like "a.b."
+	groups []string // opened groups, passed to ReplaceAttr
+	attrs  []byte   // preformatted attributes from WithAttrs
+
+	template []templatePart // layout set by WithTextTemplate, strict logfmt if nil
+}
+
+func newLogfmtHandler(w io.Writer, opts *slog.HandlerOptions) *logfmtHandler {
+	h := &logfmtHandler{w: w, mu: &sync.Mutex{}}
+	if opts != nil {
+		h.opts = *opts
+	}
+	return h
+}
+
+func (h *logfmtHandler) Enabled(_ context.Context, level slog.Level) bool {
+	minLevel := slog.LevelInfo
+	if h.opts.Level != nil {
+		minLevel = h.opts.Level.Level()
+	}
+	return level >= minLevel
+}
+
+func (h *logfmtHandler) Handle(_ context.Context, r slog.Record) error {
+	if h.template != nil {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		_, err := h.w.Write(h.handleTemplate(r))
+		return err
+	}
+
+	var buf []byte
+	if !r.Time.IsZero() {
+		buf = h.appendAttr(buf, "", nil, slog.Time(slog.TimeKey, r.Time.Round(0)))
+	}
+	buf = h.appendAttr(buf, "", nil, slog.Any(slog.LevelKey, r.Level))
+	if h.opts.AddSource {
+		frame, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+		source := &slog.Source{Function: frame.Function, File: frame.File, Line: frame.Line}
+		buf = h.appendAttr(buf, "", nil, slog.Any(slog.SourceKey, source))
+	}
+	buf = h.appendAttr(buf, "", nil, slog.String(slog.MessageKey, r.Message))
+	if len(h.attrs) > 0 {
+		buf = appendSep(buf)
+		buf = append(buf, h.attrs...)
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		buf = h.appendAttr(buf, h.prefix, h.groups, a)
+		return true
+	})
+	buf = append(buf, '\n')
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := h.w.Write(buf)
+	return err
+}
+
+func (h *logfmtHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	h2 := h.clone()
+	for _, a := range attrs {
+		h2.attrs = h2.appendAttr(h2.attrs, h2.prefix, h2.groups, a)
+	}
+	return h2
+}
+
+func (h *logfmtHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h2 := h.clone()
+	h2.prefix += name + "."
+	h2.groups = append(h2.groups, name)
+	return h2
+}
+
+func (h *logfmtHandler) clone() *logfmtHandler {
+	h2 := *h
+	h2.groups = h.groups[:len(h.groups):len(h.groups)]
+	h2.attrs = h.attrs[:len(h.attrs):len(h.attrs)]
+	return &h2
+}
+
+func (h *logfmtHandler) appendAttr(buf []byte, prefix string, groups []string, a slog.Attr) []byte {
+	if rep := h.opts.ReplaceAttr; rep != nil && a.Value.Kind() != slog.KindGroup {
+		a.Value = a.Value.Resolve()
+		a = rep(groups, a)
+	}
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return buf
+	}
+
+	if a.Value.Kind() == slog.KindGroup {
+		attrs := a.Value.Group()
+		if len(attrs) == 0 {
+			return buf
+		}
+		if a.Key != "" {
+			prefix += a.Key + "."
+			groups = append(groups[:len(groups):len(groups)], a.Key)
+		}
+		for _, aa := range attrs {
+			buf = h.appendAttr(buf, prefix, groups, aa)
+		}
+		return buf
+	}
+
+	buf = appendSep(buf)
+	buf = append(buf, logfmtKey(prefix+a.Key)...)
+	buf = append(buf, '=')
+	return append(buf, logfmtValue(a.Value)...)
+}
+
+func appendSep(buf []byte) []byte {
+	if len(buf) > 0 {
+		buf = append(buf, ' ')
+	}
+	return buf
+}
+
+// logfmtKey replace characters which are not allowed in a logfmt key with '_'
+func logfmtKey(key string) string {
+	if key == "" {
+		return "!EMPTYKEY"
+	}
+	return strings.Map(func(r rune) rune {
+		if r <= ' ' || r == '=' || r == '"' || !unicode.IsPrint(r) {
+			return '_'
+		}
+		return r
+	}, key)
+}
+
+func logfmtValue(v slog.Value) string {
+	var s string
+	switch v.Kind() {
+	case slog.KindString:
+		s = v.String()
+	case slog.KindTime:
+		s = v.Time().Format(time.RFC3339Nano)
+	case slog.KindAny:
+		switch x := v.Any().(type) {
+		case *slog.Source:
+			s = fmt.Sprintf("%s:%d", x.File, x.Line)
+		case encoding.TextMarshaler:
+			data, err := x.MarshalText()
+			if err != nil {
+				s = "!ERROR:" + err.Error()
+			} else {
+				s = string(data)
+			}
+		case error:
+			s = x.Error()
+		default:
+			s = fmt.Sprint(x)
+		}
+	default:
+		s = v.String()
+	}
+
+	if needsQuote(s) {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+func needsQuote(s string) bool {
+	if s == "" {
+		return true
+	}
+	for _, r := range s {
+		if r <= ' ' || r == '=' || r == '"' || r == '\\' || !unicode.IsPrint(r) {
+			return true
+		}
+	}
+	return false
+}
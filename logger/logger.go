@@ -2,28 +2,154 @@ package logger
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"golang.org/x/exp/slog"
 )
 
 // Init logger
 func Init(opts ...Option) {
+	o := newOption(opts)
+	o.levelVar = &loggerLevel
+
+	var fns []func() error
+	file, _ := o.writer.(*dailyFile)
+	if o.bufferSize > 0 {
+		w := newBufferedWriter(o.writer, o.bufferSize)
+		o.writer = w
+		fns = append(fns, w.Flush)
+	}
+	if file != nil {
+		fns = append(fns, file.Sync)
+	}
+	for _, out := range o.outputs {
+		if file, ok := out.writer.(*appendFile); ok {
+			fns = append(fns, file.Sync)
+		}
+	}
+	var ring *ringWriter
+	if o.ringSize > 0 {
+		ring = newRingWriter(o.ringSize)
+		o.outputs = append(o.outputs, output{writer: ring, format: o.format, loggerLevel: true})
+	}
+
+	o.prepare()
+	setAlerter(o.alerter)
+	resetLevel(levelMap[o.level])
+	strictArgs.Store(o.strictArgs)
+	current.Lock()
+	current.option = o
+	slog.SetDefault(o.newLogger())
+	current.Unlock()
+	setSyncers(fns, o.flushInterval)
+	recent.Lock()
+	recent.ring = ring
+	recent.Unlock()
+	summary.Lock()
+	summary.counts = o.counts
+	summary.Unlock()
+	setTerminate(o.exitFunc, o.panicFunc)
+}
+
+// NewHandler return the handler configured by opts like Init, without installing it as the default logger,
+// for code using slog directly like `slog.New(logger.NewHandler(logger.JSONOutput()))`.
+// its level is not changed by SetLevel, and WithBuffer, WithFlushInterval, WithRingBuffer, WithStrictArgs,
+// WithSummaryOnClose, WithExitFunc and WithPanicFunc have no effect, since they work with the default logger
+func NewHandler(opts ...Option) slog.Handler {
+	o := newOption(opts)
+	o.levelVar = &slog.LevelVar{}
+	o.levelVar.Set(levelMap[o.level])
+	o.prepare()
+	return o.handler()
+}
+
+func newOption(opts []Option) option {
 	o := defaultOption
+	o.attrs = map[string]any{} // not shared with defaultOption
 	for _, opt := range opts {
 		opt(&o)
 	}
+	return o
+}
+
+// prepare create the state shared by the handlers built from o
+func (o *option) prepare() {
+	if o.sequence {
+		o.seq = &atomic.Uint64{} // shared by loggers rebuilt by SetAttrs
+	}
+	if o.rateLimit > 0 {
+		o.limiter = newRateLimiter(o.rateLimit, o.ratePer)
+	}
+	if len(o.alerts) > 0 {
+		o.alerter = newAlerter()
+	}
+	if o.summaryOnClose {
+		o.counts = &levelCounts{start: time.Now()}
+	}
+}
+
+// current is the option of the default logger set by Init, the writers are already wrapped
+var current struct {
+	sync.Mutex
+	option option
+}
+
+// SetAttr set a base attribute of the default logger after Init, like WithAttr
+func SetAttr(key string, value any) {
+	SetAttrs(map[string]any{key: value})
+}
+
+// SetAttrs set base attributes of the default logger after Init, like WithAttr.
+// the default logger is replaced with one writing to the same outputs, records being logged are not lost
+func SetAttrs(attrs map[string]any) {
+	current.Lock()
+	defer current.Unlock()
+	o := current.option
+	o.attrs = make(map[string]any, len(current.option.attrs)+len(attrs))
+	for k, v := range current.option.attrs {
+		o.attrs[k] = v
+	}
+	for k, v := range attrs {
+		o.attrs[k] = v
+	}
+	current.option = o
 	slog.SetDefault(o.newLogger())
 }
 
 type Option func(option *option)
 
-// WithWriter set writer for logger. default is os.Stdout
-func WithWriter(writer io.Writer) Option {
+// Logger is the slog logger, used by the functions returning a logger instance
+type Logger = slog.Logger
+
+// Discard return a logger which discards all records. its handler is never enabled,
+// so log calls return before building records and attributes
+func Discard() *Logger {
+	return slog.New(discardHandler{})
+}
+
+// WithWriter set writer for logger. default is os.Stdout.
+// multiple writers get the same records in the same format, like stdout and a file. no writer changes nothing
+func WithWriter(writers ...io.Writer) Option {
 	return func(o *option) {
-		o.writer = writer
+		switch len(writers) {
+		case 0:
+		case 1:
+			o.writer = writers[0]
+		default:
+			o.writer = io.MultiWriter(writers...)
+		}
 	}
 }
 
@@ -34,18 +160,21 @@ func WithLevel(level LogLevel) Option {
 	}
 }
 
-// JSONOutput set whether output json format. default is false
-func JSONOutput() Option {
+// WithFormat set output format for logger. default is FormatText
+func WithFormat(format Format) Option {
 	return func(o *option) {
-		o.json = true
+		o.format = format
 	}
 }
 
-// TextOutput set whether output text format. default is false
+// JSONOutput set output json format. same as WithFormat(FormatJSON)
+func JSONOutput() Option {
+	return WithFormat(FormatJSON)
+}
+
+// TextOutput set output text format. same as WithFormat(FormatText)
 func TextOutput() Option {
-	return func(o *option) {
-		o.text = true
-	}
+	return WithFormat(FormatText)
 }
 
 // WithAttr set attributes for logger. default is empty
@@ -55,12 +184,322 @@ func WithAttr(key string, value any) Option {
 	}
 }
 
+// WithMaxValueLen set the max length of string attribute values. longer values are truncated to n characters
+// followed by "...". default is 0, means no truncation
+func WithMaxValueLen(n int) Option {
+	return func(o *option) {
+		o.maxValueLen = n
+	}
+}
+
+// WithTruncatedLen append the original length to values truncated by WithMaxValueLen, like "abc...(1024)"
+func WithTruncatedLen() Option {
+	return func(o *option) {
+		o.truncatedLen = true
+	}
+}
+
+// WithExtractor add a function which extracts attributes from the context of each record.
+// multiple extractors are applied in order
+func WithExtractor(fn func(ctx context.Context) []slog.Attr) Option {
+	return func(o *option) {
+		o.extractors = append(o.extractors, fn)
+	}
+}
+
+// WithContextValue add ctx.Value(key) as attribute attrKey to each record if it's not nil.
+// multiple context values can be added
+func WithContextValue(key any, attrKey string) Option {
+	return WithExtractor(func(ctx context.Context) []slog.Attr {
+		if v := ctx.Value(key); v != nil {
+			return []slog.Attr{slog.Any(attrKey, v)}
+		}
+		return nil
+	})
+}
+
+// WithDynamicAttr add the value returned by fn as attribute key to each record, like the deployment color
+// changing during a canary. fn is called when the record is logged, only for records enabled by level.
+// if fn panics, the value is "!PANIC: " followed by the recovered value, the record is still logged,
+// and a warning "dynamic attribute panic" is logged before it
+func WithDynamicAttr(key string, fn func() any) Option {
+	return WithExtractor(func(context.Context) []slog.Attr {
+		return []slog.Attr{slog.Any(key, dynamicValue(key, fn))}
+	})
+}
+
+// internalKey marks the ctx of records logged by the logger itself, they skip the extractors
+// so a panicking dynamic attribute doesn't warn about its own warning
+type internalKey struct{}
+
+func dynamicValue(key string, fn func() any) (v any) {
+	defer func() {
+		if r := recover(); r != nil {
+			v = fmt.Sprintf("!PANIC: %v", r)
+			w := slog.NewRecord(time.Now(), slog.LevelWarn, "dynamic attribute panic", 0)
+			w.AddAttrs(slog.String("key", key), slog.Any("panic", r))
+			_ = slog.Default().Handler().Handle(context.WithValue(context.Background(), internalKey{}, true), w)
+		}
+	}()
+	return fn()
+}
+
+// WithDeadlineRemaining add the time left until the deadline of ctx as attribute "deadline_remaining_ms",
+// computed when the record is logged. it's omitted if ctx has no deadline, like records logged without ctx
+func WithDeadlineRemaining() Option {
+	return WithExtractor(func(ctx context.Context) []slog.Attr {
+		if deadline, ok := ctx.Deadline(); ok {
+			return []slog.Attr{slog.Int64("deadline_remaining_ms", time.Until(deadline).Milliseconds())}
+		}
+		return nil
+	})
+}
+
+// WithFilter add a function deciding whether a record is logged, records are dropped if it returns false.
+// it's called with the attributes of the logger and the record, only for records enabled by level.
+// a panicking filter keeps the record. multiple filters must all pass
+func WithFilter(fn func(level LogLevel, msg string, attrs []slog.Attr) bool) Option {
+	return func(o *option) {
+		o.filters = append(o.filters, fn)
+	}
+}
+
+// WithRateLimit log at most n records with the same message in each window of per, the others are dropped.
+// the first record logged after dropping carries attribute "dropped" with the number of records dropped since
+// the last logged one of the message
+func WithRateLimit(n int, per time.Duration) Option {
+	return func(o *option) {
+		o.rateLimit = n
+		o.ratePer = per
+	}
+}
+
+// WithAlert call fn with the records matching predicate, like paging on severity=critical.
+// fn is called in a separate goroutine one record at a time, records are dropped if fn can't keep up,
+// see AlertsDropped. predicate is called with the attributes of the logger and the record.
+// records below the logger level are matched too and then dropped, so with alerts every log call builds
+// its record whatever the level
+func WithAlert(predicate func(level LogLevel, msg string, attrs []slog.Attr) bool, fn func(Record)) Option {
+	return func(o *option) {
+		o.alerts = append(o.alerts, alert{predicate: predicate, fn: fn})
+	}
+}
+
+// WithClock set the function providing the time of records, like a fixed time in tests. default is time.Now
+func WithClock(fn func() time.Time) Option {
+	return func(o *option) {
+		o.clock = fn
+	}
+}
+
+// WithProcessInfo add host, pid and version attributes to the logger. host is empty if it can't be resolved
+func WithProcessInfo(version string) Option {
+	return func(o *option) {
+		host, _ := os.Hostname()
+		o.attrs["host"] = host
+		o.attrs["pid"] = os.Getpid()
+		o.attrs["version"] = version
+	}
+}
+
+// WithErrorChain log error attributes as an array of {message, type} objects,
+// one for each error in the chain of errors.Unwrap
+func WithErrorChain() Option {
+	return func(o *option) {
+		o.errorChain = true
+	}
+}
+
+// WithOutput add an output to the logger besides the writer, with its own format and level
+func WithOutput(w io.Writer, format Format, level LogLevel) Option {
+	return func(o *option) {
+		o.outputs = append(o.outputs, output{writer: w, format: format, level: level})
+	}
+}
+
+// WithFilteredOutput add an output to the logger besides the writer, which only receives the records
+// matching predicate. predicate is called with the record level, and the attributes of the logger and the record.
+// the output receives records at the level of the logger
+func WithFilteredOutput(w io.Writer, format Format, predicate func(level LogLevel, attrs []slog.Attr) bool) Option {
+	return func(o *option) {
+		o.outputs = append(o.outputs, output{writer: w, format: format, predicate: predicate})
+	}
+}
+
+// WithConsoleAndFile write text records to stdout at consoleLevel, and json records to the file at path
+// at fileLevel. the file is created if not exists and appended to
+func WithConsoleAndFile(path string, consoleLevel, fileLevel LogLevel) Option {
+	return func(o *option) {
+		o.writer = os.Stdout
+		o.format = FormatText
+		o.level = consoleLevel
+		o.outputs = append(o.outputs, output{writer: newAppendFile(path), format: FormatJSON, level: fileLevel})
+	}
+}
+
+// WithLowercaseLevels render level names in lowercase, like "info" and "panic". default is uppercase
+func WithLowercaseLevels() Option {
+	return func(o *option) {
+		o.lowercaseLevels = true
+	}
+}
+
+// WithLevelKey set the key of the level attribute, like "log.level" for ECS. default is "level"
+func WithLevelKey(key string) Option {
+	return func(o *option) {
+		o.levelKey = key
+	}
+}
+
+// WithKeyPrefix prepend prefix to the keys of top level attributes, except the built-in time, level, msg and source.
+// top level groups, including the ones opened by WithGroup, get the prefix on their name. keys already having
+// the prefix are kept, and keys in groups are not prefixed
+func WithKeyPrefix(prefix string) Option {
+	return func(o *option) {
+		o.keyPrefix = prefix
+	}
+}
+
+// WithFunction add the caller's function name as attribute "func" when source is enabled by WithSource.
+// the package path prefix is stripped, like "logger.Info"
+func WithFunction() Option {
+	return func(o *option) {
+		o.function = true
+	}
+}
+
+// WithBuffer buffer the writer with a bufio.Writer of size, buffered records are written when the buffer is full,
+// or flushed by Sync and WithFlushInterval. default is 0, means not buffered
+func WithBuffer(size int) Option {
+	return func(o *option) {
+		o.bufferSize = size
+	}
+}
+
+// WithFlushInterval flush the buffer of WithBuffer periodically, so records aren't held during low traffic
+func WithFlushInterval(d time.Duration) Option {
+	return func(o *option) {
+		o.flushInterval = d
+	}
+}
+
+// WithErrorTracking track whether any error or panic record is logged, reported by HadErrors
+func WithErrorTracking() Option {
+	return func(o *option) {
+		o.trackError = true
+	}
+}
+
+// WithSchemaVersion add the schema version as attribute "schema" to each record, and set the output format to json,
+// which writes one json object per line
+func WithSchemaVersion(v int) Option {
+	return func(o *option) {
+		o.attrs["schema"] = v
+		o.format = FormatJSON
+	}
+}
+
+// WithReplaceAttr add a function rewriting attributes like slog.HandlerOptions.ReplaceAttr.
+// it's called after the built-in rewriting, like level naming, so it receives the level as a string.
+// multiple functions are called in the order they are added
+func WithReplaceAttr(fn func(groups []string, a slog.Attr) slog.Attr) Option {
+	return func(o *option) {
+		o.replaceAttrs = append(o.replaceAttrs, fn)
+	}
+}
+
+// WithDailyFile write to the file dir/prefix-YYYY-MM-DD.log, switching to a new file at local midnight
+func WithDailyFile(dir, prefix string) Option {
+	return WithDailyFileIn(dir, prefix, time.Local)
+}
+
+// WithDailyFileIn is like WithDailyFile, but switches files at midnight of loc
+func WithDailyFileIn(dir, prefix string, loc *time.Location) Option {
+	return func(o *option) {
+		o.writer = newDailyFile(dir, prefix, loc)
+	}
+}
+
+// WithCompactCollections render slice and map attributes as json in all formats, instead of the fmt rendering
+// of the text format. collections are truncated by WithMaxValueLen as well
+func WithCompactCollections() Option {
+	return func(o *option) {
+		o.compactCollections = true
+	}
+}
+
+// WithSequence add a sequence number as attribute "seq" to each record, which increases in the order
+// records are handled, across goroutines
+func WithSequence() Option {
+	return func(o *option) {
+		o.sequence = true
+	}
+}
+
+// WithOmitEmpty drop attributes whose value is an empty string, nil, or empty slice or map.
+// zero numbers and false are kept, use WithOmitZero to drop them as well
+func WithOmitEmpty() Option {
+	return func(o *option) {
+		o.omitEmpty = true
+	}
+}
+
+// WithOmitZero drop attributes with zero numbers and false besides the empty values of WithOmitEmpty
+func WithOmitZero() Option {
+	return func(o *option) {
+		o.omitEmpty = true
+		o.omitZero = true
+	}
+}
+
+// WithLargeIntsAsStrings render integers beyond 2^53 as strings in json format, so javascript consumers don't
+// lose precision of large ids. smaller integers are kept as numbers
+func WithLargeIntsAsStrings() Option {
+	return func(o *option) {
+		o.largeIntsAsStrings = true
+	}
+}
+
+// WithRingBuffer keep the most recent n formatted records in memory besides the outputs, returned by RecentLogs
+func WithRingBuffer(n int) Option {
+	return func(o *option) {
+		o.ringSize = n
+	}
+}
+
 func WithSource() Option {
 	return func(o *option) {
 		o.addSource = true
 	}
 }
 
+// SourceConfig configures the source of records in one place, see WithSourceConfig
+type SourceConfig struct {
+	Enabled     bool     // add the source file and line, like WithSource
+	MinLevel    LogLevel // only add the source to records at least at this level
+	Short       bool     // only keep the base name of the source file
+	IncludeFunc bool     // add the caller's function name, like WithFunction
+}
+
+// WithSourceConfig set all the source options at once
+func WithSourceConfig(config SourceConfig) Option {
+	return func(o *option) {
+		o.addSource = config.Enabled
+		o.sourceLevel = config.MinLevel
+		o.shortSource = config.Short
+		o.function = config.IncludeFunc
+	}
+}
+
+// Format is the output format of a logger.
+type Format int
+
+const (
+	FormatText Format = iota
+	FormatJSON
+	FormatLogfmt
+)
+
 // LogLevel is the level of a logger.
 type LogLevel int
 
@@ -70,92 +509,151 @@ const (
 	LevelWarn
 	LevelError
 	LevelPanic
+	LevelFatal
 )
 
 // Debug show debug log
 func Debug(msg string, args ...any) {
-	DebugWithCtx(context.Background(), msg, args...)
+	logAt(context.Background(), slog.LevelDebug, msg, args...)
 }
 
 func DebugWithCtx(ctx context.Context, msg string, args ...any) {
-	slog.DebugCtx(ctx, msg, args...)
+	logAt(ctx, slog.LevelDebug, msg, args...)
 }
 
 func DebugF(format string, v ...any) {
-	DebugFWithCtx(context.Background(), format, v...)
+	logAt(context.Background(), slog.LevelDebug, fmt.Sprintf(format, v...))
 }
 
 func DebugFWithCtx(ctx context.Context, format string, v ...any) {
-	slog.DebugCtx(ctx, fmt.Sprintf(format, v...))
+	logAt(ctx, slog.LevelDebug, fmt.Sprintf(format, v...))
 }
 
 func Info(msg string, args ...any) {
-	InfoWithCtx(context.Background(), msg, args...)
+	logAt(context.Background(), slog.LevelInfo, msg, args...)
 }
 
 func InfoWithCtx(ctx context.Context, msg string, args ...any) {
-	slog.InfoCtx(ctx, msg, args...)
+	logAt(ctx, slog.LevelInfo, msg, args...)
 }
 
 func InfoF(format string, v ...any) {
-	InfoFWithCtx(context.Background(), format, v...)
+	logAt(context.Background(), slog.LevelInfo, fmt.Sprintf(format, v...))
 }
 
 func InfoFWithCtx(ctx context.Context, format string, v ...any) {
-	slog.InfoCtx(ctx, fmt.Sprintf(format, v...))
+	logAt(ctx, slog.LevelInfo, fmt.Sprintf(format, v...))
 }
 
 func Warn(msg string, args ...any) {
-	WarnWithCtx(context.Background(), msg, args...)
+	logAt(context.Background(), slog.LevelWarn, msg, args...)
 }
 
 func WarnWithCtx(ctx context.Context, msg string, args ...any) {
-	slog.WarnCtx(ctx, msg, args...)
+	logAt(ctx, slog.LevelWarn, msg, args...)
 }
 
 func WarnF(format string, v ...any) {
-	WarnFWithCtx(context.Background(), format, v...)
+	logAt(context.Background(), slog.LevelWarn, fmt.Sprintf(format, v...))
 }
 
 func WarnFWithCtx(ctx context.Context, format string, v ...any) {
-	slog.WarnCtx(ctx, fmt.Sprintf(format, v...))
+	logAt(ctx, slog.LevelWarn, fmt.Sprintf(format, v...))
 }
 
 func Error(msg string, args ...any) {
-	ErrorWithCtx(context.Background(), msg, args...)
+	logAt(context.Background(), slog.LevelError, msg, args...)
 }
 
 func ErrorWithCtx(ctx context.Context, msg string, args ...any) {
-	slog.ErrorCtx(ctx, msg, args...)
+	logAt(ctx, slog.LevelError, msg, args...)
 }
 
 func ErrorF(format string, v ...any) {
-	ErrorFWithCtx(context.Background(), format, v...)
+	logAt(context.Background(), slog.LevelError, fmt.Sprintf(format, v...))
 }
 
 func ErrorFWithCtx(ctx context.Context, format string, v ...any) {
-	slog.ErrorCtx(ctx, fmt.Sprintf(format, v...))
+	logAt(ctx, slog.LevelError, fmt.Sprintf(format, v...))
 }
 
 func Panic(msg string, args ...any) {
-	PanicWithCtx(context.Background(), msg, args...)
+	logAt(context.Background(), slogLevelPanic, msg, args...)
+	doPanic(panicMessage(msg, args))
 }
 
 func PanicWithCtx(ctx context.Context, msg string, args ...any) {
-	slog.Log(ctx, slogLevelPanic, msg, args...)
-	messages := make([]interface{}, 0, len(args)+1)
-	messages = append(messages, msg)
-	messages = append(messages, args...)
-	panic(fmt.Sprint(messages...))
+	logAt(ctx, slogLevelPanic, msg, args...)
+	doPanic(panicMessage(msg, args))
 }
 
 func PanicF(format string, v ...any) {
-	PanicFWithCtx(context.Background(), format, v...)
+	logAt(context.Background(), slogLevelPanic, fmt.Sprintf(format, v...))
+	doPanic(fmt.Sprintf(format, v...))
 }
 
 func PanicFWithCtx(ctx context.Context, format string, v ...any) {
-	slog.Log(ctx, slogLevelPanic, fmt.Sprintf(format, v...))
-	panic(fmt.Sprintf(format, v...))
+	logAt(ctx, slogLevelPanic, fmt.Sprintf(format, v...))
+	doPanic(fmt.Sprintf(format, v...))
+}
+
+// Fatal log at fatal level, flush buffered records like Sync, then exit the process with code 1
+func Fatal(msg string, args ...any) {
+	logAt(context.Background(), slogLevelFatal, msg, args...)
+	exit(1)
+}
+
+func FatalWithCtx(ctx context.Context, msg string, args ...any) {
+	logAt(ctx, slogLevelFatal, msg, args...)
+	exit(1)
+}
+
+func FatalF(format string, v ...any) {
+	logAt(context.Background(), slogLevelFatal, fmt.Sprintf(format, v...))
+	exit(1)
+}
+
+func FatalFWithCtx(ctx context.Context, format string, v ...any) {
+	logAt(ctx, slogLevelFatal, fmt.Sprintf(format, v...))
+	exit(1)
+}
+
+// LogErr log err with key "error" at error level and return it, like `return logger.LogErr(ctx, "save failed", err)`.
+// nothing is logged if err is nil
+func LogErr(ctx context.Context, msg string, err error, args ...any) error {
+	if err == nil {
+		return nil
+	}
+	logAt(ctx, slog.LevelError, msg, append(args, slog.Any("error", err))...)
+	return err
+}
+
+func panicMessage(msg string, args []any) string {
+	messages := make([]interface{}, 0, len(args)+1)
+	messages = append(messages, msg)
+	messages = append(messages, args...)
+	return fmt.Sprint(messages...)
+}
+
+// logAt log with the default logger. it must be called directly by the exported logging functions,
+// since it uses a fixed call depth to get the caller's pc for the source
+func logAt(ctx context.Context, level slog.Level, msg string, args ...any) {
+	l := slog.Default()
+	if !l.Enabled(ctx, level) {
+		return
+	}
+	var pcs [1]uintptr
+	runtime.Callers(3, pcs[:]) // skip [runtime.Callers, logAt, the exported function]
+	if strictArgs.Load() {
+		if problem := checkArgs(args); problem != "" {
+			w := slog.NewRecord(time.Now(), slog.LevelWarn, "malformed log arguments", pcs[0])
+			w.AddAttrs(slog.String("problem", problem), slog.String("record_msg", msg))
+			_ = l.Handler().Handle(ctx, w)
+		}
+	}
+	r := slog.NewRecord(time.Now(), level, msg, pcs[0])
+	r.Add(args...)
+	_ = l.Handler().Handle(ctx, r)
 }
 
 func init() {
@@ -166,7 +664,7 @@ var defaultOption = option{
 	writer:    os.Stdout,
 	addSource: false,
 	level:     LevelInfo,
-	json:      false,
+	format:    FormatText,
 	attrs:     map[string]any{},
 }
 
@@ -174,9 +672,58 @@ type option struct {
 	writer    io.Writer
 	addSource bool
 	level     LogLevel
-	json      bool
-	text      bool
+	format    Format
 	attrs     map[string]any
+
+	maxValueLen  int
+	truncatedLen bool
+	extractors   []func(ctx context.Context) []slog.Attr
+	filters      []func(level LogLevel, msg string, attrs []slog.Attr) bool
+	rateLimit    int
+	ratePer      time.Duration
+	limiter      *rateLimiter
+	alerts       []alert
+	tees         []tee
+	strictArgs   bool
+	textTemplate string
+	levelVar     *slog.LevelVar // level of the logger, loggerLevel for the default logger
+	alerter      *alerter
+	clock        func() time.Time
+	errorChain   bool
+	outputs      []output
+
+	lowercaseLevels bool
+	levelKey        string
+	keyPrefix       string
+	function        bool
+	sourceLevel     LogLevel
+	shortSource     bool
+
+	bufferSize    int
+	flushInterval time.Duration
+	trackError    bool
+	replaceAttrs  []func(groups []string, a slog.Attr) slog.Attr
+
+	compactCollections bool
+	sequence           bool
+	seq                *atomic.Uint64
+	ringSize           int
+	omitEmpty          bool
+	omitZero           bool
+	largeIntsAsStrings bool
+	exitFunc           func(code int)
+	panicFunc          func(v any)
+	summaryOnClose     bool
+	counts             *levelCounts
+}
+
+type output struct {
+	writer      io.Writer
+	format      Format
+	level       LogLevel
+	loggerLevel bool // follow the level of the logger changed by SetLevel, instead of level
+	predicate   func(level LogLevel, attrs []slog.Attr) bool
+	audit       bool
 }
 
 var levelMap = map[LogLevel]slog.Level{
@@ -185,37 +732,74 @@ var levelMap = map[LogLevel]slog.Level{
 	LevelWarn:  slog.LevelWarn,
 	LevelError: slog.LevelError,
 	LevelPanic: slogLevelPanic,
+	LevelFatal: slogLevelFatal,
 }
 
-const slogLevelPanic = slog.Level(12)
+const (
+	slogLevelPanic = slog.Level(12)
+	slogLevelFatal = slog.Level(16)
+)
+
+// toLogLevel return the highest LogLevel not above the slog level
+func toLogLevel(level slog.Level) LogLevel {
+	switch {
+	case level >= slogLevelFatal:
+		return LevelFatal
+	case level >= slogLevelPanic:
+		return LevelPanic
+	case level >= slog.LevelError:
+		return LevelError
+	case level >= slog.LevelWarn:
+		return LevelWarn
+	case level >= slog.LevelInfo:
+		return LevelInfo
+	default:
+		return LevelDebug
+	}
+}
 
 var sLogLevelName = map[slog.Level]string{
 	slogLevelPanic: "PANIC",
+	slogLevelFatal: "FATAL",
 }
 
 func (o *option) newLogger() *slog.Logger {
-	var h slog.Handler
-	handlerOps := slog.HandlerOptions{
-		AddSource: o.addSource,
-		Level:     levelMap[o.level],
-		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
-			if a.Key == slog.LevelKey {
-				level := a.Value.Any().(slog.Level)
-				name, ok := sLogLevelName[level]
-				if !ok {
-					name = level.String()
-				}
-				a.Value = slog.StringValue(name)
-			}
-			return a
-		},
+	return slog.New(o.handler())
+}
+
+func (o *option) handler() slog.Handler {
+	h := &handler{
+		extractors:  o.extractors,
+		filters:     o.filters,
+		limiter:     o.limiter,
+		alerts:      o.alerts,
+		tees:        o.tees,
+		alerter:     o.alerter,
+		function:    o.addSource && o.function,
+		sourceLevel: levelMap[o.sourceLevel],
+		trackError:  o.trackError,
+		keyPrefix:   o.keyPrefix,
 	}
-
-	if o.json {
-		h = slog.NewJSONHandler(o.writer, &handlerOps)
-	} else {
-		h = slog.NewTextHandler(o.writer, &handlerOps)
+	if o.sequence {
+		h.seq = o.seq
 	}
+	h.counts = o.counts
+	h.sinks = append(h.sinks, sink{Handler: o.newHandler(o.writer, o.format, o.levelVar), primary: true})
+	if o.format != FormatJSON { // for records logged with AsJSON, built on the first one
+		w := o.writer
+		lazy := newLazyHandler(o.levelVar, func() slog.Handler { return o.newHandler(w, FormatJSON, o.levelVar) })
+		h.sinks = append(h.sinks, sink{Handler: lazy, json: true})
+		h.hasJSON = true
+	}
+	for _, out := range o.outputs {
+		var level slog.Leveler = levelMap[out.level]
+		if out.predicate != nil || out.loggerLevel { // filtered outputs use the level of the logger
+			level = o.levelVar
+		}
+		h.sinks = append(h.sinks, sink{Handler: o.newHandler(out.writer, out.format, level), predicate: out.predicate, audit: out.audit})
+		h.hasAudit = h.hasAudit || out.audit
+	}
+
 	if len(o.attrs) > 0 {
 		attrs := make([]slog.Attr, 0, len(o.attrs))
 		for k, v := range o.attrs {
@@ -224,8 +808,187 @@ func (o *option) newLogger() *slog.Logger {
 				Value: slog.AnyValue(v),
 			})
 		}
-		h = h.WithAttrs(attrs)
+		return h.WithAttrs(attrs)
+	}
+	return h
+}
+
+func (o *option) newHandler(w io.Writer, format Format, level slog.Leveler) slog.Handler {
+	handlerOps := slog.HandlerOptions{
+		AddSource: o.addSource,
+		Level:     level,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			return o.replaceAttr(format, groups, a)
+		},
+	}
+
+	switch format {
+	case FormatJSON:
+		return slog.NewJSONHandler(w, &handlerOps)
+	case FormatLogfmt:
+		return newLogfmtHandler(w, &handlerOps)
+	default:
+		if o.textTemplate != "" {
+			h := newLogfmtHandler(w, &handlerOps)
+			h.template = parseTemplate(o.textTemplate)
+			return h
+		}
+		return slog.NewTextHandler(w, &handlerOps)
+	}
+}
+
+func (o *option) replaceAttr(format Format, groups []string, a slog.Attr) slog.Attr {
+	if o.omitEmpty && !(len(groups) == 0 && isBuiltinKey(a.Key)) && o.isEmpty(a.Value) {
+		return slog.Attr{}
+	}
+	if o.keyPrefix != "" && len(groups) == 0 && !isBuiltinKey(a.Key) && !strings.HasPrefix(a.Key, o.keyPrefix) {
+		a.Key = o.keyPrefix + a.Key
+	}
+	if o.clock != nil && len(groups) == 0 && a.Key == slog.TimeKey && a.Value.Kind() == slog.KindTime {
+		a.Value = slog.TimeValue(o.clock())
+	}
+	if level, ok := a.Value.Any().(slog.Level); ok && len(groups) == 0 && a.Key == slog.LevelKey {
+		name, ok := sLogLevelName[level]
+		if !ok {
+			name = level.String()
+		}
+		if o.lowercaseLevels {
+			name = strings.ToLower(name)
+		}
+		a.Value = slog.StringValue(name)
+		if o.levelKey != "" {
+			a.Key = o.levelKey
+		}
+	}
+	if source, ok := a.Value.Any().(*slog.Source); ok && len(groups) == 0 && a.Key == slog.SourceKey {
+		if source.File == "" { // the pc is cleared for records under the source min level
+			return slog.Attr{}
+		}
+		if o.shortSource {
+			a.Value = slog.AnyValue(&slog.Source{Function: source.Function, File: filepath.Base(source.File), Line: source.Line})
+		}
+	}
+	if o.largeIntsAsStrings && format == FormatJSON {
+		a.Value = largeIntAsString(a.Value)
+	}
+	if o.compactCollections && a.Value.Kind() == slog.KindAny {
+		a.Value = o.compactCollection(format, a.Value)
+	}
+	if o.maxValueLen > 0 && a.Value.Kind() == slog.KindString && !(len(groups) == 0 && a.Key == slog.MessageKey) {
+		a.Value = slog.StringValue(o.truncate(a.Value.String()))
+	}
+	if o.errorChain && a.Value.Kind() == slog.KindAny {
+		if err, ok := a.Value.Any().(error); ok {
+			a.Value = slog.AnyValue(errorChain(err))
+		}
+	}
+	for _, fn := range o.replaceAttrs {
+		a = fn(groups, a)
+	}
+	return a
+}
+
+// compactCollection render slices and maps as json strings, except for json format which renders them natively.
+// in json format, collections longer than WithMaxValueLen are rendered as strings, so they can be truncated
+func (o *option) compactCollection(format Format, v slog.Value) slog.Value {
+	switch reflect.ValueOf(v.Any()).Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		if _, ok := v.Any().([]byte); ok {
+			return v
+		}
+	default:
+		return v
+	}
+
+	data, err := json.Marshal(v.Any())
+	if err != nil {
+		return v
+	}
+	if format == FormatJSON && (o.maxValueLen <= 0 || len(data) <= o.maxValueLen) {
+		return v
+	}
+	return slog.StringValue(string(data))
+}
+
+// isEmpty report whether the value is dropped by WithOmitEmpty or WithOmitZero
+func (o *option) isEmpty(v slog.Value) bool {
+	switch v.Kind() {
+	case slog.KindString:
+		return v.String() == ""
+	case slog.KindInt64:
+		return o.omitZero && v.Int64() == 0
+	case slog.KindUint64:
+		return o.omitZero && v.Uint64() == 0
+	case slog.KindFloat64:
+		return o.omitZero && v.Float64() == 0
+	case slog.KindBool:
+		return o.omitZero && !v.Bool()
+	case slog.KindDuration:
+		return o.omitZero && v.Duration() == 0
+	case slog.KindAny:
+		if v.Any() == nil {
+			return true
+		}
+		rv := reflect.ValueOf(v.Any())
+		switch rv.Kind() {
+		case reflect.Slice, reflect.Map:
+			return rv.Len() == 0
+		case reflect.Pointer, reflect.Interface:
+			return rv.IsNil()
+		}
+	}
+	return false
+}
+
+// maxSafeInt is the largest integer which a float64 represents exactly, as javascript numbers
+const maxSafeInt = 1 << 53
+
+// largeIntAsString render integers beyond maxSafeInt as strings
+func largeIntAsString(v slog.Value) slog.Value {
+	switch v.Kind() {
+	case slog.KindInt64:
+		if n := v.Int64(); n > maxSafeInt || n < -maxSafeInt {
+			return slog.StringValue(strconv.FormatInt(n, 10))
+		}
+	case slog.KindUint64:
+		if n := v.Uint64(); n > maxSafeInt {
+			return slog.StringValue(strconv.FormatUint(n, 10))
+		}
+	}
+	return v
+}
+
+func isBuiltinKey(key string) bool {
+	switch key {
+	case slog.TimeKey, slog.LevelKey, slog.MessageKey, slog.SourceKey:
+		return true
+	}
+	return false
+}
+
+type errorLayer struct {
+	Message string `json:"message"`
+	Type    string `json:"type"`
+}
+
+func errorChain(err error) []errorLayer {
+	var chain []errorLayer
+	for ; err != nil; err = errors.Unwrap(err) {
+		chain = append(chain, errorLayer{Message: err.Error(), Type: fmt.Sprintf("%T", err)})
 	}
+	return chain
+}
 
-	return slog.New(h)
+func (o *option) truncate(s string) string {
+	if len(s) <= o.maxValueLen {
+		return s
+	}
+	runes := []rune(s)
+	if len(runes) <= o.maxValueLen {
+		return s
+	}
+	if o.truncatedLen {
+		return fmt.Sprintf("%s...(%d)", string(runes[:o.maxValueLen]), len(runes))
+	}
+	return string(runes[:o.maxValueLen]) + "..."
 }
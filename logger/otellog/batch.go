@@ -0,0 +1,155 @@
+package otellog
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// batcher collects records and exports them from a single goroutine, when a batch is full, on each interval,
+// and on flush or shutdown. it's shared by handlers derived from the same handler
+type batcher struct {
+	exporter Exporter
+	size     int
+	onError  func(err error)
+
+	mu     sync.Mutex
+	batch  []Record
+	closed bool
+	adding sync.WaitGroup // adds sending a full batch, waited by shutdown before closing batches
+
+	batches chan []Record      // full batches, closed by shutdown
+	flushes chan chan struct{} // flush requests, the channel is closed after exporting
+	done    chan struct{}      // closed when the exporting goroutine exits
+	stopCtx context.Context    // ctx of shutdown, set before closing batches
+	stopErr error              // error of the exporter's Shutdown, set before closing done
+}
+
+func newBatcher(exporter Exporter, size int, interval time.Duration, onError func(err error)) *batcher {
+	b := &batcher{
+		exporter: exporter,
+		size:     size,
+		onError:  onError,
+		batches:  make(chan []Record),
+		flushes:  make(chan chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go b.run(interval)
+	return b
+}
+
+// add append the record to the batch, and hand the batch to the exporting goroutine if it's full.
+// it blocks while the goroutine is exporting the previous full batch
+func (b *batcher) add(rec Record) {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return
+	}
+	b.batch = append(b.batch, rec)
+	if len(b.batch) < b.size {
+		b.mu.Unlock()
+		return
+	}
+	full := b.batch
+	b.batch = nil
+	b.adding.Add(1)
+	b.mu.Unlock()
+
+	defer b.adding.Done()
+	b.batches <- full
+}
+
+// take return the records of the unfilled batch
+func (b *batcher) take() []Record {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	batch := b.batch
+	b.batch = nil
+	return batch
+}
+
+func (b *batcher) run(interval time.Duration) {
+	defer close(b.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case batch, ok := <-b.batches:
+			if !ok {
+				b.export(b.take())
+				b.stopErr = b.exporter.Shutdown(b.stopCtx)
+				return
+			}
+			b.export(batch)
+		case <-ticker.C:
+			b.export(b.take())
+		case exported := <-b.flushes:
+			b.drain()
+			b.export(b.take())
+			close(exported)
+		}
+	}
+}
+
+// drain export the full batches being sent, so a flush exports records in order
+func (b *batcher) drain() {
+	for {
+		select {
+		case batch, ok := <-b.batches:
+			if !ok {
+				return
+			}
+			b.export(batch)
+		default:
+			return
+		}
+	}
+}
+
+func (b *batcher) export(batch []Record) {
+	if len(batch) == 0 {
+		return
+	}
+	if err := b.exporter.Export(context.Background(), batch); err != nil && b.onError != nil {
+		b.onError(err)
+	}
+}
+
+func (b *batcher) flush(ctx context.Context) error {
+	exported := make(chan struct{})
+	select {
+	case b.flushes <- exported:
+	case <-b.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case <-exported:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (b *batcher) shutdown(ctx context.Context) error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return nil
+	}
+	b.closed = true
+	b.mu.Unlock()
+
+	b.adding.Wait()
+	b.stopCtx = ctx
+	close(b.batches)
+	select {
+	case <-b.done:
+		return b.stopErr
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
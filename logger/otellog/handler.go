@@ -0,0 +1,157 @@
+package otellog
+
+import (
+	"context"
+	"time"
+
+	"golang.org/x/exp/slog"
+)
+
+// Handler is a slog handler converting records into OpenTelemetry log records for the exporter,
+// like `logger := slog.New(otellog.NewHandler(exporter))`. call Shutdown before exiting to export the last batch
+type Handler struct {
+	batcher     *batcher
+	level       slog.Leveler
+	spanContext func(ctx context.Context) (SpanContext, bool)
+	goas        []groupOrAttrs // added by WithAttrs and WithGroup, in order
+}
+
+// groupOrAttrs is either a group name or attributes added to the handler
+type groupOrAttrs struct {
+	group string
+	attrs []slog.Attr
+}
+
+// NewHandler return a handler exporting records in batches to exporter
+func NewHandler(exporter Exporter, opts ...Option) *Handler {
+	o := option{batchSize: defaultBatchSize, interval: defaultInterval, level: slog.LevelInfo}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.batchSize < 1 {
+		o.batchSize = 1
+	}
+	if o.interval <= 0 {
+		o.interval = defaultInterval
+	}
+	return &Handler{
+		batcher:     newBatcher(exporter, o.batchSize, o.interval, o.onError),
+		level:       o.level,
+		spanContext: o.spanContext,
+	}
+}
+
+func (h *Handler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+func (h *Handler) Handle(ctx context.Context, r slog.Record) error {
+	rec := Record{
+		Timestamp:         r.Time,
+		ObservedTimestamp: time.Now(),
+		SeverityNumber:    Severity(r.Level),
+		SeverityText:      severityText(r.Level),
+		Body:              r.Message,
+		Attributes:        h.attributes(r),
+	}
+	if h.spanContext != nil {
+		if sc, ok := h.spanContext(ctx); ok {
+			rec.TraceID, rec.SpanID, rec.TraceFlags = sc.TraceID, sc.SpanID, sc.TraceFlags
+		}
+	}
+	h.batcher.add(rec)
+	return nil
+}
+
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	return h.with(groupOrAttrs{attrs: attrs})
+}
+
+func (h *Handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return h.with(groupOrAttrs{group: name})
+}
+
+func (h *Handler) with(goa groupOrAttrs) *Handler {
+	h2 := *h
+	h2.goas = append(h.goas[:len(h.goas):len(h.goas)], goa)
+	return &h2
+}
+
+// ForceFlush export the records not exported yet, and block until the exporter returns
+func (h *Handler) ForceFlush(ctx context.Context) error {
+	return h.batcher.flush(ctx)
+}
+
+// Shutdown export the records not exported yet and shut down the exporter. records handled after Shutdown
+// are dropped. it returns the error of the exporter's Shutdown, or ctx.Err() if ctx is done before that
+func (h *Handler) Shutdown(ctx context.Context) error {
+	return h.batcher.shutdown(ctx)
+}
+
+// attributes convert the attributes of the handler and the record, a group nests the attributes added after it
+func (h *Handler) attributes(r slog.Record) []KeyValue {
+	kvs := make([]KeyValue, 0, r.NumAttrs())
+	r.Attrs(func(a slog.Attr) bool {
+		kvs = appendAttr(kvs, a)
+		return true
+	})
+	for i := len(h.goas) - 1; i >= 0; i-- {
+		goa := h.goas[i]
+		if goa.group != "" {
+			if len(kvs) > 0 { // empty groups are omitted like slog
+				kvs = []KeyValue{{Key: goa.group, Value: kvs}}
+			}
+			continue
+		}
+		attrs := make([]KeyValue, 0, len(goa.attrs)+len(kvs))
+		for _, a := range goa.attrs {
+			attrs = appendAttr(attrs, a)
+		}
+		kvs = append(attrs, kvs...)
+	}
+	return kvs
+}
+
+func appendAttr(kvs []KeyValue, a slog.Attr) []KeyValue {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return kvs
+	}
+
+	var v any
+	switch a.Value.Kind() {
+	case slog.KindString:
+		v = a.Value.String()
+	case slog.KindInt64:
+		v = a.Value.Int64()
+	case slog.KindUint64:
+		v = a.Value.Uint64()
+	case slog.KindFloat64:
+		v = a.Value.Float64()
+	case slog.KindBool:
+		v = a.Value.Bool()
+	case slog.KindTime:
+		v = a.Value.Time().Format(time.RFC3339Nano)
+	case slog.KindGroup:
+		group := make([]KeyValue, 0, len(a.Value.Group()))
+		for _, ga := range a.Value.Group() {
+			group = appendAttr(group, ga)
+		}
+		if a.Key == "" { // inline group
+			return append(kvs, group...)
+		}
+		if len(group) == 0 {
+			return kvs
+		}
+		v = group
+	default: // duration and any
+		v = a.Value.String()
+	}
+	return append(kvs, KeyValue{Key: a.Key, Value: v})
+}
@@ -0,0 +1,121 @@
+// Package otellog converts slog records into OpenTelemetry log records, and exports them in batches.
+// it defines the records and the Exporter itself, so the package has no OpenTelemetry dependency,
+// an Exporter is an adapter to an OTLP exporter of the OpenTelemetry SDK
+package otellog
+
+import (
+	"context"
+	"time"
+
+	"golang.org/x/exp/slog"
+)
+
+// Record is an OpenTelemetry log record
+type Record struct {
+	Timestamp         time.Time
+	ObservedTimestamp time.Time
+	SeverityNumber    int // 1 to 24, see Severity
+	SeverityText      string
+	Body              string
+	Attributes        []KeyValue
+	TraceID           [16]byte // zero if the record isn't in a span
+	SpanID            [8]byte
+	TraceFlags        byte
+}
+
+// KeyValue is an attribute of a record. Value is one of string, int64, uint64, float64, bool, []KeyValue for
+// groups, or the string of other values formatted by slog
+type KeyValue struct {
+	Key   string
+	Value any
+}
+
+// Exporter exports batches of records, like an OTLP exporter.
+// Export isn't called concurrently, and the records are not reused after it returns
+type Exporter interface {
+	Export(ctx context.Context, records []Record) error
+	Shutdown(ctx context.Context) error
+}
+
+// SpanContext is the trace context of a record, returned by the function set by WithSpanContext
+type SpanContext struct {
+	TraceID    [16]byte
+	SpanID     [8]byte
+	TraceFlags byte
+}
+
+const (
+	defaultBatchSize = 512
+	defaultInterval  = time.Second
+)
+
+type option struct {
+	batchSize   int
+	interval    time.Duration
+	spanContext func(ctx context.Context) (SpanContext, bool)
+	onError     func(err error)
+	level       slog.Leveler
+}
+
+type Option func(o *option)
+
+// WithBatchSize set the max records of a batch, a full batch is exported at once. default is 512
+func WithBatchSize(n int) Option {
+	return func(o *option) {
+		o.batchSize = n
+	}
+}
+
+// WithExportInterval set how often the records of an unfilled batch are exported. default is 1s
+func WithExportInterval(d time.Duration) Option {
+	return func(o *option) {
+		o.interval = d
+	}
+}
+
+// WithSpanContext set the function returning the trace context of ctx, like
+// `trace.SpanContextFromContext(ctx)` of OpenTelemetry. records without it have no trace and span ids
+func WithSpanContext(fn func(ctx context.Context) (SpanContext, bool)) Option {
+	return func(o *option) {
+		o.spanContext = fn
+	}
+}
+
+// WithErrorHandler set the function called with errors of the exporter. default ignores them
+func WithErrorHandler(fn func(err error)) Option {
+	return func(o *option) {
+		o.onError = fn
+	}
+}
+
+// WithLevel set the min level of exported records. default is slog.LevelInfo
+func WithLevel(level slog.Leveler) Option {
+	return func(o *option) {
+		o.level = level
+	}
+}
+
+// Severity return the OpenTelemetry severity number of the slog level.
+// debug is 5, info is 9, warn is 13, error is 17, the panic level (12) of the logger package is 21 (fatal),
+// and its fatal level (16) is 24
+func Severity(level slog.Level) int {
+	n := 9 + int(level)
+	switch {
+	case n < 1:
+		return 1
+	case n > 24:
+		return 24
+	}
+	return n
+}
+
+// severityText return the level name, PANIC and FATAL for the panic and fatal levels of the logger package
+func severityText(level slog.Level) string {
+	switch level {
+	case slog.LevelError + 4:
+		return "PANIC"
+	case slog.LevelError + 8:
+		return "FATAL"
+	}
+	return level.String()
+}
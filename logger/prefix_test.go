@@ -0,0 +1,30 @@
+package logger
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"golang.org/x/exp/slog"
+)
+
+func TestKeyPrefixGroups(t *testing.T) {
+	defer Init()
+	var buf bytes.Buffer
+	Init(WithWriter(&buf), WithKeyPrefix("app."))
+
+	Info("m", "a", 1, slog.Group("g", "b", 2), slog.Group("app.p", "c", 3))
+	slog.Default().WithGroup("h").Info("m", "c", 3, slog.Group("n", "d", 4))
+	slog.Default().With(slog.Group("w", "e", 5)).WithGroup("h").With(slog.Group("n", "f", 6)).Info("m")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	for i, want := range []string{
+		"app.a=1 app.g.b=2 app.p.c=3",
+		"app.h.c=3 app.h.n.d=4",
+		"app.w.e=5 app.h.n.f=6",
+	} {
+		if !strings.HasSuffix(lines[i], want) {
+			t.Errorf("line %d %q, want suffix %q", i, lines[i], want)
+		}
+	}
+}
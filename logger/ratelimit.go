@@ -0,0 +1,62 @@
+package logger
+
+import (
+	"sync"
+	"time"
+)
+
+// rateLimiter allows at most n records per message in each window of per, and counts the dropped ones
+type rateLimiter struct {
+	n   int
+	per time.Duration
+
+	mu      sync.Mutex
+	windows map[string]*rateWindow
+}
+
+type rateWindow struct {
+	start   time.Time
+	count   int // records allowed in the window
+	dropped int // records dropped since the last allowed one
+}
+
+// maxRateWindows is the number of windows kept before expired ones are pruned
+const maxRateWindows = 1024
+
+func newRateLimiter(n int, per time.Duration) *rateLimiter {
+	return &rateLimiter{n: n, per: per, windows: map[string]*rateWindow{}}
+}
+
+// allow report whether a record of key at now is logged, and the records of key dropped before it
+func (l *rateLimiter) allow(key string, now time.Time) (ok bool, dropped int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	w := l.windows[key]
+	if w == nil {
+		if len(l.windows) >= maxRateWindows {
+			l.prune(now)
+		}
+		w = &rateWindow{start: now}
+		l.windows[key] = w
+	}
+	if now.Sub(w.start) >= l.per {
+		w.start, w.count = now, 0
+	}
+	if w.count >= l.n {
+		w.dropped++
+		return false, 0
+	}
+	w.count++
+	dropped, w.dropped = w.dropped, 0
+	return true, dropped
+}
+
+// prune remove expired windows without dropped records
+func (l *rateLimiter) prune(now time.Time) {
+	for key, w := range l.windows {
+		if w.dropped == 0 && now.Sub(w.start) >= l.per {
+			delete(l.windows, key)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package logger
+
+import (
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	reconnectMinBackoff = 100 * time.Millisecond
+	reconnectMaxBackoff = 10 * time.Second
+	reconnectTimeout    = 5 * time.Second // write deadline of each record
+)
+
+// reconnectDropped counts the records dropped by reconnecting writers because the buffer is full
+var reconnectDropped atomic.Uint64
+
+// ReconnectDropped return the number of records dropped by WithReconnectingWriter while disconnected
+func ReconnectDropped() uint64 {
+	return reconnectDropped.Load()
+}
+
+// WithReconnectingWriter write records to the connection returned by dial, like a tcp log collector.
+// while disconnected, up to bufferSize records are kept and the oldest ones are dropped, see ReconnectDropped.
+// dial is retried with backoff, and the kept records are written first once connected
+func WithReconnectingWriter(dial func() (net.Conn, error), bufferSize int) Option {
+	return func(o *option) {
+		o.writer = newReconnectWriter(dial, bufferSize)
+	}
+}
+
+type reconnectWriter struct {
+	dial func() (net.Conn, error)
+	size int
+
+	mu      sync.Mutex
+	conn    net.Conn // nil while disconnected
+	buf     [][]byte // records kept while disconnected, oldest first
+	dialing bool
+}
+
+func newReconnectWriter(dial func() (net.Conn, error), size int) *reconnectWriter {
+	return &reconnectWriter{dial: dial, size: size}
+}
+
+func (w *reconnectWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.conn != nil {
+		if err := w.write(p); err == nil {
+			return len(p), nil
+		}
+	}
+	w.keep(append([]byte(nil), p...)) // p is reused by the handler
+	if !w.dialing {
+		w.dialing = true
+		go w.redial()
+	}
+	return len(p), nil
+}
+
+// write p to the connection, the connection is dropped on error
+func (w *reconnectWriter) write(p []byte) error {
+	_ = w.conn.SetWriteDeadline(time.Now().Add(reconnectTimeout))
+	if _, err := w.conn.Write(p); err != nil {
+		_ = w.conn.Close()
+		w.conn = nil
+		return err
+	}
+	return nil
+}
+
+func (w *reconnectWriter) keep(p []byte) {
+	if w.size <= 0 {
+		reconnectDropped.Add(1)
+		return
+	}
+	if len(w.buf) >= w.size {
+		w.buf = w.buf[1:]
+		reconnectDropped.Add(1)
+	}
+	w.buf = append(w.buf, p)
+}
+
+// redial connect with backoff until the kept records are written to a new connection
+func (w *reconnectWriter) redial() {
+	backoff := reconnectMinBackoff
+	for {
+		if conn, err := w.dial(); err == nil {
+			w.mu.Lock()
+			w.conn = conn
+			for len(w.buf) > 0 && w.write(w.buf[0]) == nil {
+				w.buf = w.buf[1:]
+			}
+			if w.conn != nil {
+				w.dialing = false
+				w.mu.Unlock()
+				return
+			}
+			w.mu.Unlock()
+		}
+
+		time.Sleep(backoff)
+		if backoff *= 2; backoff > reconnectMaxBackoff {
+			backoff = reconnectMaxBackoff
+		}
+	}
+}
@@ -0,0 +1,16 @@
+package logger
+
+import (
+	"context"
+	"runtime/debug"
+)
+
+// PoolRecover return a recover function for groutine_pool.WithRecover, which logs the recovered value
+// and the stack at error level. attributes extracted from ctx, like the request id, are logged as well.
+// ctx is the one passed to PoolRecover, the pool doesn't pass the ctx of the panicking task to the recover
+// function, so the worker id of the task is not logged
+func PoolRecover(ctx context.Context) func(r any) {
+	return func(r any) {
+		ErrorWithCtx(ctx, "task panic", "panic", r, "stack", string(debug.Stack()))
+	}
+}
@@ -0,0 +1,57 @@
+package logger
+
+import (
+	"context"
+	"runtime"
+	"time"
+
+	"golang.org/x/exp/slog"
+)
+
+type contextKey string
+
+// RequestIDKey is the ctx key of the request id set by WithRequestID. records logged with a ctx
+// having a request id get the attribute "request_id"
+const RequestIDKey contextKey = "request_id"
+
+// WithRequestID return a ctx with the request id, like in the middleware of a server
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, RequestIDKey, id)
+}
+
+// RequestID return the request id of ctx set by WithRequestID, it's empty if not set
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(RequestIDKey).(string)
+	return id
+}
+
+// LogRequest log an outbound http call with standardized keys.
+// level is error if err is not nil or status >= 500, warn if status >= 400, otherwise info
+func LogRequest(ctx context.Context, method, url string, status int, latency time.Duration, err error) {
+	l := slog.Default()
+	level := slog.LevelInfo
+	switch {
+	case err != nil || status >= 500:
+		level = slog.LevelError
+	case status >= 400:
+		level = slog.LevelWarn
+	}
+
+	if !l.Enabled(ctx, level) {
+		return
+	}
+
+	var pcs [1]uintptr
+	runtime.Callers(2, pcs[:]) // skip [runtime.Callers, LogRequest]
+	r := slog.NewRecord(time.Now(), level, "http request", pcs[0])
+	r.AddAttrs(
+		slog.String("http.method", method),
+		slog.String("http.url", url),
+		slog.Int("http.status", status),
+		slog.Float64("http.latency_ms", float64(latency)/float64(time.Millisecond)),
+	)
+	if err != nil {
+		r.AddAttrs(slog.Any("error", err))
+	}
+	_ = l.Handler().Handle(ctx, r)
+}
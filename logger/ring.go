@@ -0,0 +1,57 @@
+package logger
+
+import (
+	"strings"
+	"sync"
+)
+
+// recent is the ring buffer installed by Init with WithRingBuffer
+var recent struct {
+	sync.Mutex
+	ring *ringWriter
+}
+
+// RecentLogs return the most recent records kept by WithRingBuffer, oldest first
+func RecentLogs() []string {
+	recent.Lock()
+	ring := recent.ring
+	recent.Unlock()
+	if ring == nil {
+		return nil
+	}
+	return ring.records()
+}
+
+// ringWriter keeps the most recent n writes, each write is a formatted record
+type ringWriter struct {
+	mu   sync.Mutex
+	buf  []string
+	next int
+	full bool
+}
+
+func newRingWriter(n int) *ringWriter {
+	return &ringWriter{buf: make([]string, n)}
+}
+
+func (w *ringWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.buf[w.next] = strings.TrimSuffix(string(p), "\n")
+	w.next = (w.next + 1) % len(w.buf)
+	if w.next == 0 {
+		w.full = true
+	}
+	return len(p), nil
+}
+
+func (w *ringWriter) records() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.full {
+		return append([]string(nil), w.buf[:w.next]...)
+	}
+	records := make([]string, 0, len(w.buf))
+	records = append(records, w.buf[w.next:]...)
+	return append(records, w.buf[:w.next]...)
+}
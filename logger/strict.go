@@ -0,0 +1,37 @@
+package logger
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"golang.org/x/exp/slog"
+)
+
+// strictArgs is set by WithStrictArgs
+var strictArgs atomic.Bool
+
+// WithStrictArgs check the key value arguments of the logging functions, like a key without value.
+// a malformed call logs a warning "malformed log arguments" at the call site before the record,
+// which slog logs with !BADKEY attributes. it's meant for development
+func WithStrictArgs() Option {
+	return func(o *option) {
+		o.strictArgs = true
+	}
+}
+
+// checkArgs return the problem of args as slog parses them, it's empty if args are well formed
+func checkArgs(args []any) string {
+	for i := 0; i < len(args); i++ {
+		switch key := args[i].(type) {
+		case slog.Attr:
+		case string:
+			if i+1 == len(args) {
+				return fmt.Sprintf("missing value for key %q", key)
+			}
+			i++
+		default:
+			return fmt.Sprintf("key %v of type %T at position %d is not a string or slog.Attr", key, key, i)
+		}
+	}
+	return ""
+}
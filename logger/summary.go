@@ -0,0 +1,68 @@
+package logger
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/exp/slog"
+)
+
+// WithSummaryOnClose log a summary record when Close is called, with the count of records
+// logged at each level and the runtime since Init, like the end-of-run digest of a batch job.
+// records dropped by the level, filters or rate limit are not counted
+func WithSummaryOnClose() Option {
+	return func(o *option) {
+		o.summaryOnClose = true
+	}
+}
+
+// levelCounts counts records per level for the summary, shared by handlers derived from the same logger
+type levelCounts struct {
+	start  time.Time
+	counts [LevelFatal + 1]atomic.Int64
+}
+
+func (c *levelCounts) add(level slog.Level) {
+	c.counts[toLogLevel(level)].Add(1)
+}
+
+// summaryKey marks the summary record, which is written regardless of the level
+type summaryKey struct{}
+
+func isSummary(ctx context.Context) bool {
+	return ctx.Value(summaryKey{}) != nil
+}
+
+// summaryKeys are the keys of the counts in the summary record, indexed by LogLevel
+var summaryKeys = [...]string{"debug", "info", "warn", "error", "panic", "fatal"}
+
+// summary is the counts of the default logger set by Init, nil after Close
+var summary struct {
+	sync.Mutex
+	counts *levelCounts
+}
+
+// Close log the summary record if WithSummaryOnClose is set, then flush buffered records like Sync.
+// the summary is logged at info level even if the level of the logger is higher, and is not dropped by
+// filters or rate limit. it's logged once, the logger is still usable after Close
+func Close() error {
+	summary.Lock()
+	counts := summary.counts
+	summary.counts = nil
+	summary.Unlock()
+
+	if counts != nil {
+		var pcs [1]uintptr
+		runtime.Callers(2, pcs[:]) // skip [runtime.Callers, Close]
+		r := slog.NewRecord(time.Now(), slog.LevelInfo, "log summary", pcs[0])
+		for level := range counts.counts {
+			r.AddAttrs(slog.Int64(summaryKeys[level], counts.counts[level].Load()))
+		}
+		r.AddAttrs(slog.Duration("runtime", time.Since(counts.start)))
+		_ = slog.Default().Handler().Handle(context.WithValue(context.Background(), summaryKey{}, true), r)
+	}
+	return Sync()
+}
@@ -0,0 +1,29 @@
+package logger
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestSummaryOnCloseAboveLevel(t *testing.T) {
+	defer Init()
+	var buf bytes.Buffer
+	Init(WithWriter(&buf), WithSummaryOnClose(), WithLevel(LevelWarn), WithSource())
+	Info("dropped")
+	Warn("kept")
+	if err := Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2:\n%s", len(lines), buf.String())
+	}
+	summary := lines[1]
+	for _, want := range []string{`msg="log summary"`, "info=0", "warn=1", "summary_test.go:"} {
+		if !strings.Contains(summary, want) {
+			t.Errorf("summary %q doesn't contain %q", summary, want)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package logger
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"sync"
+	"time"
+)
+
+// syncers of the logger installed by Init, called by Sync
+var syncers struct {
+	sync.Mutex
+	fns  []func() error
+	stop chan struct{} // stop the flushing goroutine of the previous Init
+}
+
+// Sync flush buffered records of the logger
+func Sync() error {
+	syncers.Lock()
+	defer syncers.Unlock()
+	return syncAll(syncers.fns)
+}
+
+func syncAll(fns []func() error) error {
+	var errs []error
+	for _, fn := range fns {
+		if err := fn(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// setSyncers replace the syncers of the previous Init, which are synced before replacing.
+// if interval > 0, the syncers are called periodically
+func setSyncers(fns []func() error, interval time.Duration) {
+	syncers.Lock()
+	defer syncers.Unlock()
+
+	_ = syncAll(syncers.fns)
+	if syncers.stop != nil {
+		close(syncers.stop)
+		syncers.stop = nil
+	}
+	syncers.fns = fns
+	if interval <= 0 || len(fns) == 0 {
+		return
+	}
+
+	stop := make(chan struct{})
+	syncers.stop = stop
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				_ = syncAll(fns)
+			}
+		}
+	}()
+}
+
+// bufferedWriter is a concurrency safe bufio.Writer
+type bufferedWriter struct {
+	mu sync.Mutex
+	w  *bufio.Writer
+}
+
+func newBufferedWriter(w io.Writer, size int) *bufferedWriter {
+	return &bufferedWriter{w: bufio.NewWriterSize(w, size)}
+}
+
+func (b *bufferedWriter) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.w.Write(p)
+}
+
+func (b *bufferedWriter) Flush() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.w.Flush()
+}
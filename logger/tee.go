@@ -0,0 +1,35 @@
+package logger
+
+// TeePolicy is what WithTeeChannel does when the channel is full
+type TeePolicy int
+
+const (
+	// DropNewest drop the record which doesn't fit in the channel, logging is never blocked by the consumer
+	DropNewest TeePolicy = iota
+	// Block wait for the consumer, so no record is lost but logging is as slow as the consumer
+	Block
+)
+
+type tee struct {
+	ch     chan<- Record
+	onFull TeePolicy
+}
+
+func (t tee) send(r Record) {
+	if t.onFull == Block {
+		t.ch <- r
+		return
+	}
+	select {
+	case t.ch <- r:
+	default:
+	}
+}
+
+// WithTeeChannel send each logged record to ch besides the outputs, like live tailing in a dashboard.
+// onFull decides whether to drop records or block logging when ch is full. ch is never closed by the logger
+func WithTeeChannel(ch chan<- Record, onFull TeePolicy) Option {
+	return func(o *option) {
+		o.tees = append(o.tees, tee{ch: ch, onFull: onFull})
+	}
+}
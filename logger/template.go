@@ -0,0 +1,109 @@
+package logger
+
+import (
+	"bytes"
+	"runtime"
+	"strings"
+
+	"golang.org/x/exp/slog"
+)
+
+// DefaultTextTemplate is the template of WithTextTemplate matching the layout of the text format
+const DefaultTextTemplate = "time={time} level={level} {source}msg={msg} {attrs}"
+
+// templateTimeFormat is the time layout of templates, the same as the text format
+const templateTimeFormat = "2006-01-02T15:04:05.000Z07:00"
+
+// WithTextTemplate set the layout of the text format, like "[{level}] {time} {msg} {attrs}".
+// the placeholders are {time}, {level} and {msg} for the values, {source} for "source=file:line " with a trailing
+// space if source is enabled, and {attrs} for the key=value attributes. other text is written as is, including unknown placeholders.
+// values are quoted like strconv.Quote if they are empty or contain spaces, '=', '"', '\\' or
+// non-printable characters, so a record is always one line. trailing spaces of a line are trimmed
+func WithTextTemplate(tmpl string) Option {
+	return func(o *option) {
+		o.textTemplate = tmpl
+	}
+}
+
+// templatePart is a literal text or a placeholder of a template
+type templatePart struct {
+	literal string
+	field   string // the name of the placeholder, empty for literal
+}
+
+func parseTemplate(tmpl string) []templatePart {
+	var parts []templatePart
+	for tmpl != "" {
+		start := strings.IndexByte(tmpl, '{')
+		end := strings.IndexByte(tmpl[start+1:], '}') + start + 1
+		if start < 0 || end <= start {
+			parts = append(parts, templatePart{literal: tmpl})
+			break
+		}
+		switch field := tmpl[start+1 : end]; field {
+		case "time", "level", "msg", "source", "attrs":
+			parts = append(parts, templatePart{literal: tmpl[:start]}, templatePart{field: field})
+		default:
+			parts = append(parts, templatePart{literal: tmpl[:end+1]})
+		}
+		tmpl = tmpl[end+1:]
+	}
+	return parts
+}
+
+// handleTemplate format the record by the template of the handler
+func (h *logfmtHandler) handleTemplate(r slog.Record) []byte {
+	var buf []byte
+	for _, part := range h.template {
+		switch part.field {
+		case "":
+			buf = append(buf, part.literal...)
+		case "time":
+			if !r.Time.IsZero() {
+				buf = appendTemplateValue(buf, h.replaceBuiltin(slog.Time(slog.TimeKey, r.Time.Round(0))))
+			}
+		case "level":
+			buf = appendTemplateValue(buf, h.replaceBuiltin(slog.Any(slog.LevelKey, r.Level)))
+		case "msg":
+			buf = appendTemplateValue(buf, h.replaceBuiltin(slog.String(slog.MessageKey, r.Message)))
+		case "source":
+			if h.opts.AddSource {
+				frame, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+				source := &slog.Source{Function: frame.Function, File: frame.File, Line: frame.Line}
+				if a := h.replaceBuiltin(slog.Any(slog.SourceKey, source)); !a.Equal(slog.Attr{}) {
+					buf = append(buf, a.Key...)
+					buf = append(appendTemplateValue(append(buf, '='), a), ' ')
+				}
+			}
+		case "attrs":
+			attrs := h.attrs[:len(h.attrs):len(h.attrs)]
+			r.Attrs(func(a slog.Attr) bool {
+				attrs = h.appendAttr(attrs, h.prefix, h.groups, a)
+				return true
+			})
+			buf = append(buf, attrs...)
+		}
+	}
+	buf = bytes.TrimRight(buf, " ")
+	return append(buf, '\n')
+}
+
+// replaceBuiltin call ReplaceAttr with a built-in attribute
+func (h *logfmtHandler) replaceBuiltin(a slog.Attr) slog.Attr {
+	if rep := h.opts.ReplaceAttr; rep != nil {
+		a.Value = a.Value.Resolve()
+		a = rep(nil, a)
+	}
+	a.Value = a.Value.Resolve()
+	return a
+}
+
+func appendTemplateValue(buf []byte, a slog.Attr) []byte {
+	if a.Equal(slog.Attr{}) {
+		return buf
+	}
+	if a.Value.Kind() == slog.KindTime {
+		return append(buf, a.Value.Time().Format(templateTimeFormat)...)
+	}
+	return append(buf, logfmtValue(a.Value)...)
+}
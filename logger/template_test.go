@@ -0,0 +1,33 @@
+package logger
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"golang.org/x/exp/slog"
+)
+
+func TestDefaultTextTemplateMatchesText(t *testing.T) {
+	defer Init()
+	now := time.Date(2024, 1, 2, 3, 4, 5, 6e6, time.UTC)
+	render := func(opts ...Option) string {
+		var buf bytes.Buffer
+		Init(append(opts, WithWriter(&buf), WithClock(func() time.Time { return now }), WithAttr("app", "x"))...)
+		Info("hello world", "k", "v w", "n", 1)
+		slog.Default().With("g", 1).Warn("with")
+		return buf.String()
+	}
+
+	for _, source := range []bool{false, true} {
+		var opts []Option
+		if source {
+			opts = append(opts, WithSource())
+		}
+		text := render(opts...)
+		tmpl := render(append(opts, WithTextTemplate(DefaultTextTemplate))...)
+		if text != tmpl {
+			t.Errorf("source %v: template output differs from text\ntext:     %s\ntemplate: %s", source, text, tmpl)
+		}
+	}
+}
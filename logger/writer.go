@@ -0,0 +1,52 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log"
+	"runtime"
+	"strings"
+	"time"
+
+	"golang.org/x/exp/slog"
+)
+
+// Writer return an io.Writer which writes each call as a log record at the given level
+func Writer(level LogLevel) io.Writer {
+	return &levelWriter{level: levelMap[level]}
+}
+
+// StdLogger return a standard library logger which writes to this logger at the given level
+func StdLogger(level LogLevel) *log.Logger {
+	return log.New(Writer(level), "", 0)
+}
+
+type levelWriter struct {
+	level slog.Level
+}
+
+func (w *levelWriter) Write(p []byte) (int, error) {
+	l := slog.Default()
+	if !l.Enabled(context.Background(), w.level) {
+		return len(p), nil
+	}
+	msg := bytes.TrimSuffix(p, []byte{'\n'})
+	r := slog.NewRecord(time.Now(), w.level, string(msg), callerPC())
+	_ = l.Handler().Handle(context.Background(), r)
+	return len(p), nil
+}
+
+// callerPC return the pc of the caller writing to levelWriter, skipping the frames of the standard log package
+// for StdLogger. it's 0 if there is no such frame
+func callerPC() uintptr {
+	var pcs [16]uintptr
+	n := runtime.Callers(3, pcs[:]) // skip [runtime.Callers, callerPC, levelWriter.Write]
+	for _, pc := range pcs[:n] {
+		frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+		if !strings.HasPrefix(frame.Function, "log.") {
+			return pc
+		}
+	}
+	return 0
+}